@@ -0,0 +1,113 @@
+package crzerolog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/rs/zerolog"
+)
+
+// consoleEnv is the env var to enable or disable the console output, which takes precedence over Config.Console.
+const consoleEnv = "CRZEROLOG_CONSOLE"
+
+// ConsoleMode controls the human-readable console output for local development.
+type ConsoleMode int
+
+const (
+	// ConsoleAuto enables the console output if not running on Google Cloud and the output is a terminal.
+	ConsoleAuto ConsoleMode = iota
+	// ConsoleOn always enables the console output.
+	ConsoleOn
+	// ConsoleOff always disables the console output, i.e. the output is always JSON for Cloud Logging.
+	ConsoleOff
+)
+
+// useConsole reports whether the root logger writing to w should use the console output.
+func (l *Logger) useConsole(w io.Writer) bool {
+	if v, err := strconv.ParseBool(os.Getenv(consoleEnv)); err == nil {
+		return v
+	}
+
+	switch l.config.Console {
+	case ConsoleOn:
+		return true
+	case ConsoleOff:
+		return false
+	default:
+		if l.config.Platform.OnGoogleCloud() {
+			return false
+		}
+		return isTerminal(w)
+	}
+}
+
+// consoleWriter returns zerolog.ConsoleWriter writing to w, which renders the Cloud Logging fields compactly.
+func (l *Logger) consoleWriter(w io.Writer) zerolog.ConsoleWriter {
+	noColor := !isTerminal(w)
+	return zerolog.ConsoleWriter{
+		Out:             w,
+		NoColor:         noColor,
+		FormatTimestamp: l.formatConsoleTimestamp(noColor),
+		FormatPrepare: func(evt map[string]interface{}) error {
+			l.prepareConsoleEvent(evt)
+			return nil
+		},
+	}
+}
+
+// formatConsoleTimestamp returns the formatter of the time field like the default one of zerolog.ConsoleWriter,
+// which prints nothing instead of "<nil>" for the log entries without the time field,
+// e.g. the ones written by the root logger directly.
+func (l *Logger) formatConsoleTimestamp(noColor bool) zerolog.Formatter {
+	return func(i interface{}) string {
+		if i == nil {
+			return ""
+		}
+		s := fmt.Sprint(i)
+		if t, err := time.Parse(l.config.TimeFieldFormat, s); err == nil {
+			s = t.Local().Format(time.Kitchen)
+		}
+		if noColor || os.Getenv("NO_COLOR") != "" {
+			return s
+		}
+		// Dark gray, as the default formatter.
+		return "\x1b[90m" + s + "\x1b[0m"
+	}
+}
+
+// prepareConsoleEvent replaces the Cloud Logging fields in evt with the ones rendered by zerolog.ConsoleWriter.
+func (l *Logger) prepareConsoleEvent(evt map[string]interface{}) {
+	if l.config.TimeFieldName != zerolog.TimestampFieldName {
+		if t, ok := evt[l.config.TimeFieldName]; ok {
+			evt[zerolog.TimestampFieldName] = t
+			delete(evt, l.config.TimeFieldName)
+		}
+	}
+
+	if loc, ok := evt["logging.googleapis.com/sourceLocation"].(map[string]interface{}); ok {
+		evt[zerolog.CallerFieldName] = fmt.Sprintf("%v:%v", loc["file"], loc["line"])
+		delete(evt, "logging.googleapis.com/sourceLocation")
+	}
+
+	if trace, ok := evt["logging.googleapis.com/trace"].(string); ok {
+		// Show the first 8 characters of the trace ID, like the short commit hash.
+		traceID := trace[strings.LastIndex(trace, "/")+1:]
+		if len(traceID) > 8 {
+			traceID = traceID[:8]
+		}
+		evt["trace"] = traceID
+		delete(evt, "logging.googleapis.com/trace")
+		delete(evt, "logging.googleapis.com/spanId")
+		delete(evt, "logging.googleapis.com/trace_sampled")
+	}
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && isatty.IsTerminal(f.Fd())
+}
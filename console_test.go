@@ -0,0 +1,65 @@
+package crzerolog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestConsole(t *testing.T) {
+	logger := New(Config{ProjectID: "myproject", Console: ConsoleOn})
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Ctx(r.Context()).Warn().Str("foo", "bar").Msg("hello")
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Add("X-Cloud-Trace-Context", "0123456789abcdef0123456789abcdef/123;o=1")
+	logger.InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	got := buf.String()
+	want := regexp.MustCompile(`^\S+ WRN console_test\.go:\d+ > hello foo=bar trace=01234567\n$`)
+	if !want.MatchString(got) {
+		t.Errorf("got = %q, want to match %q", got, want)
+	}
+	if strings.Contains(got, "logging.googleapis.com") {
+		t.Errorf("Cloud Logging fields are not rendered: %q", got)
+	}
+}
+
+func TestConsoleWithoutTime(t *testing.T) {
+	buf := &bytes.Buffer{}
+	rootLogger := New(Config{ProjectID: "myproject", Console: ConsoleOn}).RootLogger(buf)
+	rootLogger.Warn().Msg("hello")
+
+	if got, want := buf.String(), "WRN hello\n"; got != want {
+		t.Errorf("got = %q, want = %q", got, want)
+	}
+}
+
+func TestConsoleEnv(t *testing.T) {
+	for _, tt := range []struct {
+		env     string
+		console ConsoleMode
+		want    bool
+	}{
+		{"", ConsoleAuto, false},
+		{"", ConsoleOn, true},
+		{"true", ConsoleOff, true},
+		{"false", ConsoleOn, false},
+	} {
+		t.Setenv(consoleEnv, tt.env)
+		l := New(Config{ProjectID: "myproject", Console: tt.console})
+		if got := l.useConsole(&bytes.Buffer{}); got != tt.want {
+			t.Errorf("useConsole() with %s=%q and %v = %v, want = %v", consoleEnv, tt.env, tt.console, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package crzerolog
+
+// debugLevelKey is the context key for the level of the request with the debug log.
+type debugLevelKey struct{}
+
+// debugLog reports whether the debug log is enabled for the request.
+func (l *Logger) debugLog(trace TraceContext, header func(string) string) bool {
+	if l.config.DebugLogSampled && trace.Sampled {
+		return true
+	}
+	if l.config.DebugLogHeader == "" || l.config.DebugLogAllowFunc == nil {
+		return false
+	}
+	value := header(l.config.DebugLogHeader)
+	return value != "" && l.config.DebugLogAllowFunc(value)
+}
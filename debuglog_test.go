@@ -0,0 +1,116 @@
+package crzerolog
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestDebugLog(t *testing.T) {
+	tests := []struct {
+		desc      string
+		config    Config
+		header    http.Header
+		wantDebug bool
+	}{
+		{
+			desc:      "With X-Debug-Log without DebugLogAllowFunc",
+			config:    Config{DebugLogHeader: "X-Debug-Log"},
+			header:    http.Header{"X-Debug-Log": {"1"}},
+			wantDebug: false,
+		},
+		{
+			desc:      "Without X-Debug-Log",
+			config:    Config{DebugLogHeader: "X-Debug-Log"},
+			header:    http.Header{},
+			wantDebug: false,
+		},
+		{
+			desc: "With allowed X-Debug-Log",
+			config: Config{DebugLogHeader: "X-Debug-Log", DebugLogAllowFunc: func(v string) bool {
+				return v == "secret"
+			}},
+			header:    http.Header{"X-Debug-Log": {"secret"}},
+			wantDebug: true,
+		},
+		{
+			desc: "With disallowed X-Debug-Log",
+			config: Config{DebugLogHeader: "X-Debug-Log", DebugLogAllowFunc: func(v string) bool {
+				return v == "secret"
+			}},
+			header:    http.Header{"X-Debug-Log": {"guess"}},
+			wantDebug: false,
+		},
+		{
+			desc:      "With sampled trace",
+			config:    Config{DebugLogSampled: true},
+			header:    http.Header{"X-Cloud-Trace-Context": {"0123456789abcdef0123456789abcdef/123;o=1"}},
+			wantDebug: true,
+		},
+		{
+			desc:      "With not sampled trace",
+			config:    Config{DebugLogSampled: true},
+			header:    http.Header{"X-Cloud-Trace-Context": {"0123456789abcdef0123456789abcdef/123;o=0"}},
+			wantDebug: false,
+		},
+	}
+
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	defer zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	for _, tt := range tests {
+		tt.config.ProjectID = "myproject"
+		logger := New(tt.config)
+		buf := &bytes.Buffer{}
+		rootLogger := logger.RootLogger(buf).Level(zerolog.InfoLevel)
+
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log.Ctx(r.Context()).Debug().Msg("debug")
+		})
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header = tt.header
+		logger.InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), req)
+		if got := strings.Contains(buf.String(), "debug"); got != tt.wantDebug {
+			t.Errorf("%s: debug log written = %v, want = %v", tt.desc, got, tt.wantDebug)
+		}
+
+		// The root logger is not affected.
+		buf.Reset()
+		rootLogger.Debug().Msg("debug")
+		if buf.Len() != 0 {
+			t.Errorf("%s: debug log is written by the root logger: %s", tt.desc, buf.String())
+		}
+	}
+}
+
+func allowDebugLog(value string) bool {
+	return value == "1"
+}
+
+func TestDebugLogInterceptor(t *testing.T) {
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	defer zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	logger := New(Config{ProjectID: "myproject", DebugLogHeader: "X-Debug-Log", DebugLogAllowFunc: allowDebugLog})
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf).Level(zerolog.InfoLevel)
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		log.Ctx(ctx).Debug().Msg("debug")
+		return nil, nil
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-debug-log", "1"))
+	logger.InjectLoggerInterceptor(&rootLogger)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "TestService.TestMethod"}, handler)
+
+	if !strings.Contains(buf.String(), "debug") {
+		t.Errorf("debug log is not written")
+	}
+}
@@ -0,0 +1,123 @@
+package crzerolog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// errorKey is the context key for the logged error.
+type errorKey struct{}
+
+// loggedError is the error added to the log event by Err or ErrorStackMarshaler.
+type loggedError struct {
+	err error
+	// pcs is the stack trace of the error, or nil if it has none.
+	pcs []uintptr
+}
+
+// Err returns a function for zerolog.Event.Func, which adds err to the event.
+// If err or any error in its chain has a StackTrace method like github.com/pkg/errors,
+// its stack trace is reported to Error Reporting instead of the one at the log site.
+//
+//	logger.Error().Func(crzerolog.Err(err)).Msg("Failed to process")
+func Err(err error) func(*zerolog.Event) {
+	return func(e *zerolog.Event) {
+		e.Err(err)
+		withLoggedError(e, err)
+	}
+}
+
+// ErrorStackMarshaler is a function for zerolog.ErrorStackMarshaler, which makes zerolog.Event.Err
+// report the error the same as Err, if the event has Stack enabled.
+// The injected logger has Stack enabled if Config.ErrorReporting is set.
+// The stack field has the top frame of the error's stack trace if any.
+//
+//	zerolog.ErrorStackMarshaler = crzerolog.ErrorStackMarshaler
+//	logger.Error().Stack().Err(err).Msg("Failed to process")
+func ErrorStackMarshaler(err error) interface{} {
+	return errorStackObject{err}
+}
+
+// errorStackObject implements zerolog.LogObjectMarshaler interface.
+type errorStackObject struct {
+	err error
+}
+
+// MarshalZerologObject adds the top frame of the error's stack trace to the stack field.
+// zerolog passes the event being logged to it, so the error is also stored in the event context for errorHook.
+func (o errorStackObject) MarshalZerologObject(e *zerolog.Event) {
+	pcs := withLoggedError(e, o.err)
+	if len(pcs) == 0 {
+		return
+	}
+	frame, _ := runtime.CallersFrames(pcs).Next()
+	e.Str("function", frame.Function).Str("file", frame.File).Int("line", frame.Line)
+}
+
+// withLoggedError stores err and its stack trace in the context of e, and returns the stack trace.
+func withLoggedError(e *zerolog.Event, err error) []uintptr {
+	if err == nil {
+		return nil
+	}
+	pcs := errorStack(err)
+	e.Ctx(context.WithValue(e.GetCtx(), errorKey{}, loggedError{err: err, pcs: pcs}))
+	return pcs
+}
+
+// reportedMessage returns the first line of the stack trace reported to Error Reporting,
+// which has both the log message and the error message.
+func reportedMessage(msg string, err error) string {
+	switch {
+	case err == nil && msg == "":
+		return "error"
+	case err == nil:
+		return msg
+	case msg == "":
+		return err.Error()
+	default:
+		return msg + ": " + err.Error()
+	}
+}
+
+// errorStack returns the program counters of the deepest error in the chain of err,
+// which has a StackTrace method returning a slice of uintptr, such as github.com/pkg/errors.
+func errorStack(err error) []uintptr {
+	var pcs []uintptr
+	for ; err != nil; err = errors.Unwrap(err) {
+		m := reflect.ValueOf(err).MethodByName("StackTrace")
+		if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+			continue
+		}
+		st := m.Call(nil)[0]
+		if st.Kind() != reflect.Slice || st.Type().Elem().Kind() != reflect.Uintptr {
+			continue
+		}
+		pcs = make([]uintptr, st.Len())
+		for i := range pcs {
+			pcs[i] = uintptr(st.Index(i).Uint())
+		}
+	}
+	return pcs
+}
+
+// formatStack formats the stack trace in the same format as an unrecovered panic,
+// which is recognized by Error Reporting.
+func formatStack(msg string, pcs []uintptr) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\ngoroutine 1 [running]:\n", msg)
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s(...)\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
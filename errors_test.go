@@ -0,0 +1,144 @@
+package crzerolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// frame and stackError mimic github.com/pkg/errors.
+type frame uintptr
+
+type stackError struct {
+	msg   string
+	stack []frame
+}
+
+func (e *stackError) Error() string { return e.msg }
+
+func (e *stackError) StackTrace() []frame { return e.stack }
+
+//go:noinline
+func newStackError(msg string) error {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(1, pcs)
+	stack := make([]frame, n)
+	for i := range stack {
+		stack[i] = frame(pcs[i])
+	}
+	return &stackError{msg, stack}
+}
+
+func TestErrorReporting(t *testing.T) {
+	t.Setenv("K_SERVICE", "myservice")
+
+	stackErr := newStackError("failed")
+	tests := []struct {
+		desc              string
+		handler           http.Handler
+		stackMarshaler    bool
+		wantMessage       string
+		wantFunction      string
+		wantReporting     bool
+		wantStackFunction string
+	}{
+		{
+			desc: "Error",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				log.Ctx(r.Context()).Error().Err(errors.New("failed")).Msg("hello")
+			}),
+			wantMessage:   "hello",
+			wantFunction:  "TestErrorReporting.func1",
+			wantReporting: true,
+		},
+		{
+			desc: "Error with stack",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				log.Ctx(r.Context()).Error().Func(Err(fmt.Errorf("wrapped: %w", stackErr))).Msg("hello")
+			}),
+			wantMessage:   "hello: wrapped: failed",
+			wantFunction:  "newStackError",
+			wantReporting: true,
+		},
+		{
+			desc: "Error with ErrorStackMarshaler",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				log.Ctx(r.Context()).Error().Err(fmt.Errorf("wrapped: %w", stackErr)).Msg("hello")
+			}),
+			stackMarshaler:    true,
+			wantMessage:       "hello: wrapped: failed",
+			wantFunction:      "newStackError",
+			wantReporting:     true,
+			wantStackFunction: "newStackError",
+		},
+		{
+			desc: "Error without stack with ErrorStackMarshaler",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				log.Ctx(r.Context()).Error().Err(errors.New("failed")).Send()
+			}),
+			stackMarshaler: true,
+			wantMessage:    "failed",
+			wantFunction:   "TestErrorReporting.func4",
+			wantReporting:  true,
+		},
+		{
+			desc: "Warn",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				log.Ctx(r.Context()).Warn().Err(errors.New("failed")).Msg("hello")
+			}),
+			wantReporting: false,
+		},
+	}
+
+	defer func(m func(error) interface{}) { zerolog.ErrorStackMarshaler = m }(zerolog.ErrorStackMarshaler)
+
+	for _, tt := range tests {
+		zerolog.ErrorStackMarshaler = nil
+		if tt.stackMarshaler {
+			zerolog.ErrorStackMarshaler = ErrorStackMarshaler
+		}
+		logger := New(Config{ProjectID: "myproject", ErrorReporting: true})
+		buf := &bytes.Buffer{}
+		rootLogger := logger.RootLogger(buf)
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+		logger.InjectLogger(&rootLogger)(tt.handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		var got errorReportingEntry
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if !tt.wantReporting {
+			if got.Type != "" || got.StackTrace != "" {
+				t.Errorf("%s: Unexpected Error Reporting fields: %+v", tt.desc, got)
+			}
+			continue
+		}
+		if got.Type != reportedErrorEventType || got.ServiceContext.Service != "myservice" {
+			t.Errorf("%s: Log output is not in Error Reporting format: %+v", tt.desc, got)
+		}
+		lines := strings.Split(got.StackTrace, "\n")
+		if len(lines) < 4 || lines[2] != "goroutine 1 [running]:" {
+			t.Fatalf("%s: stack_trace is not in panic format: %q", tt.desc, got.StackTrace)
+		}
+		if lines[0] != tt.wantMessage {
+			t.Errorf("%s: first line = %q, want = %q", tt.desc, lines[0], tt.wantMessage)
+		}
+		if !strings.Contains(lines[3], tt.wantFunction) {
+			t.Errorf("%s: top frame = %q, want = %q", tt.desc, lines[3], tt.wantFunction)
+		}
+		if !strings.Contains(got.Stack.Function, tt.wantStackFunction) || (tt.wantStackFunction == "") != (got.Stack.Function == "") {
+			t.Errorf("%s: stack.function = %q, want = %q", tt.desc, got.Stack.Function, tt.wantStackFunction)
+		}
+	}
+}
@@ -1,17 +1,19 @@
 module github.com/yfuruyama/crzerolog
 
-go 1.13
+go 1.21
 
 require (
-	github.com/coreos/go-systemd v0.0.0-20190321100706-95778dfbb74e // indirect
-	github.com/golang/protobuf v1.3.4 // indirect
-	github.com/google/go-cmp v0.4.0
-	github.com/rs/xid v1.4.0 // indirect
-	github.com/rs/zerolog v1.28.1-0.20220918145356-55aaf043cf4d
-	github.com/zenazn/goji v0.9.0 // indirect
+	github.com/golang/protobuf v1.3.4
+	github.com/google/go-cmp v0.6.0
+	github.com/mattn/go-isatty v0.0.19
+	github.com/rs/zerolog v1.33.0
+	google.golang.org/grpc v1.27.1
+)
+
+require (
+	github.com/mattn/go-colorable v0.1.13 // indirect
 	golang.org/x/net v0.0.0-20200301022130-244492dfa37a // indirect
+	golang.org/x/sys v0.12.0 // indirect
 	golang.org/x/text v0.3.2 // indirect
-	golang.org/x/tools v0.0.0-20190828213141-aed303cbaa74 // indirect
 	google.golang.org/genproto v0.0.0-20200306153348-d950eab6f860 // indirect
-	google.golang.org/grpc v1.27.1
 )
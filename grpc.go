@@ -2,38 +2,163 @@ package crzerolog
 
 import (
 	"context"
-	"fmt"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 // InjectLoggerInterceptor returns a gRPC unary interceptor for injecting zerolog.Logger to the RPC invocation context.
+// It sets zerolog package-level variables for Cloud Logging on first call.
 func InjectLoggerInterceptor(rootLogger *zerolog.Logger) grpc.UnaryServerInterceptor {
+	return std().InjectLoggerInterceptor(rootLogger)
+}
+
+// InjectLoggerStreamInterceptor returns a gRPC stream interceptor for injecting zerolog.Logger to the stream context.
+// It sets zerolog package-level variables for Cloud Logging on first call.
+func InjectLoggerStreamInterceptor(rootLogger *zerolog.Logger) grpc.StreamServerInterceptor {
+	return std().InjectLoggerStreamInterceptor(rootLogger)
+}
+
+// InjectLoggerInterceptor returns a gRPC unary interceptor for injecting zerolog.Logger to the RPC invocation context.
+func (l *Logger) InjectLoggerInterceptor(rootLogger *zerolog.Logger) grpc.UnaryServerInterceptor {
+	rootLogger = l.injectedRootLogger(rootLogger)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-		ctx = rootLogger.With().Timestamp().Logger().Hook(sourceLocationHook).WithContext(ctx)
+		ctx, logger := l.injectLogger(ctx, rootLogger, metadataHeader(ctx))
+		if md := l.responseMetadata(ctx); md != nil {
+			// The error is ignored because the header is informative.
+			_ = grpc.SetHeader(ctx, md)
+		}
 
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return handler(ctx, req)
+		if !l.config.RPCLog {
+			return l.handleUnary(ctx, req, info, handler, &logger)
 		}
-		values := md.Get("x-cloud-trace-context")
-		if len(values) != 1 {
-			return handler(ctx, req)
+
+		start := time.Now()
+		resp, err = l.handleUnary(ctx, req, info, handler, &logger)
+		event := l.rpcLogEvent(ctx, &logger, info.FullMethod, err, time.Since(start))
+		if m, ok := req.(proto.Message); ok {
+			event = event.Int("requestSize", proto.Size(m))
+		}
+		if m, ok := resp.(proto.Message); ok && err == nil {
+			event = event.Int("responseSize", proto.Size(m))
+		}
+		event.Msg("finished unary call")
+		return resp, err
+	}
+}
+
+// InjectLoggerStreamInterceptor returns a gRPC stream interceptor for injecting zerolog.Logger to the stream context.
+func (l *Logger) InjectLoggerStreamInterceptor(rootLogger *zerolog.Logger) grpc.StreamServerInterceptor {
+	rootLogger = l.injectedRootLogger(rootLogger)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, logger := l.injectLogger(ss.Context(), rootLogger, metadataHeader(ss.Context()))
+		if md := l.responseMetadata(ctx); md != nil {
+			// The error is ignored because the header is informative.
+			_ = ss.SetHeader(md)
 		}
 
-		traceID, _ := traceContextFromHeader(values[0])
-		if traceID == "" {
-			return handler(ctx, req)
+		if !l.config.RPCLog {
+			return l.handleStream(srv, &serverStream{ss, ctx}, info, handler, &logger)
 		}
-		trace := fmt.Sprintf("projects/%s/traces/%s", projectID, traceID)
 
-		log.Ctx(ctx).UpdateContext(func(c zerolog.Context) zerolog.Context {
-			return c.Str("logging.googleapis.com/trace", trace)
-		})
+		start := time.Now()
+		err := l.handleStream(srv, &serverStream{ss, ctx}, info, handler, &logger)
+		l.rpcLogEvent(ctx, &logger, info.FullMethod, err, time.Since(start)).Msg("finished streaming call")
+		return err
+	}
+}
+
+// responseMetadata returns the header metadata to be sent to the client, or nil if none.
+func (l *Logger) responseMetadata(ctx context.Context) metadata.MD {
+	header := l.responseHeader(ctx)
+	if len(header) == 0 {
+		return nil
+	}
+	return metadata.New(header)
+}
 
-		return handler(ctx, req)
+// handleUnary calls the unary handler, recovering from panic if configured.
+func (l *Logger) handleUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler, logger *zerolog.Logger) (resp interface{}, err error) {
+	if l.config.RecoverPanic {
+		defer l.recoverRPC(logger, info.FullMethod, &err)
+	}
+	return handler(ctx, req)
+}
+
+// handleStream calls the stream handler, recovering from panic if configured.
+func (l *Logger) handleStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler, logger *zerolog.Logger) (err error) {
+	if l.config.RecoverPanic {
+		defer l.recoverRPC(logger, info.FullMethod, &err)
+	}
+	return handler(srv, ss)
+}
+
+// rpcLogEvent returns a log event for the finished RPC, whose level is derived from the status code of err.
+func (l *Logger) rpcLogEvent(ctx context.Context, logger *zerolog.Logger, method string, err error, duration time.Duration) *zerolog.Event {
+	event := l.clientRPCLogEvent(logger, method, err, duration)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		event = event.Str("peerAddress", p.Addr.String())
+	}
+	return event
+}
+
+// clientRPCLogEvent returns a log event for the finished RPC without the peer address,
+// whose level is derived from the status code of err.
+func (l *Logger) clientRPCLogEvent(logger *zerolog.Logger, method string, err error, duration time.Duration) *zerolog.Event {
+	code := status.Code(err)
+	event := logger.WithLevel(l.config.RPCLogLevelFunc(code)).
+		Str("method", method).
+		Str("code", code.String()).
+		Str("duration", formatDuration(duration))
+	if err != nil {
+		event = event.Err(err)
+	}
+	return event
+}
+
+// CodeToLevel returns the level for the gRPC status code.
+// Client errors are mapped to WarnLevel, and server errors are mapped to ErrorLevel.
+func CodeToLevel(code codes.Code) zerolog.Level {
+	switch code {
+	case codes.OK:
+		return zerolog.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return zerolog.WarnLevel
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal,
+		codes.Unavailable, codes.DataLoss:
+		return zerolog.ErrorLevel
+	default:
+		return zerolog.ErrorLevel
+	}
+}
+
+// serverStream wraps grpc.ServerStream to replace its context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context with the injected logger.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// metadataHeader returns a function to get the value of the given key from the incoming metadata.
+func metadataHeader(ctx context.Context) func(string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return func(key string) string {
+		values := md.Get(key)
+		if len(values) != 1 {
+			return ""
+		}
+		return values[0]
 	}
 }
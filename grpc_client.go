@@ -0,0 +1,109 @@
+package crzerolog
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// TraceClientInterceptor returns a gRPC unary client interceptor which propagates the trace
+// in the context injected by InjectLogger or InjectLoggerInterceptor to the outgoing metadata.
+func TraceClientInterceptor() grpc.UnaryClientInterceptor {
+	return stdClient().TraceClientInterceptor()
+}
+
+// TraceClientStreamInterceptor returns a gRPC stream client interceptor which propagates the trace
+// in the context injected by InjectLogger or InjectLoggerInterceptor to the outgoing metadata.
+func TraceClientStreamInterceptor() grpc.StreamClientInterceptor {
+	return stdClient().TraceClientStreamInterceptor()
+}
+
+// TraceClientInterceptor returns a gRPC unary client interceptor which propagates the trace
+// in the context injected by InjectLogger or InjectLoggerInterceptor to the outgoing metadata.
+// It sets x-cloud-trace-context and traceparent with a new span ID, unless they are already set.
+func (l *Logger) TraceClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = outgoingTraceContext(ctx)
+
+		if !l.config.ClientLog {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		l.clientRPCLogEvent(log.Ctx(ctx), method, err, time.Since(start)).Msg("finished client unary call")
+		return err
+	}
+}
+
+// TraceClientStreamInterceptor returns a gRPC stream client interceptor which propagates the trace
+// in the context injected by InjectLogger or InjectLoggerInterceptor to the outgoing metadata.
+// It sets x-cloud-trace-context and traceparent with a new span ID, unless they are already set.
+func (l *Logger) TraceClientStreamInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx = outgoingTraceContext(ctx)
+
+		if !l.config.ClientLog {
+			return streamer(ctx, desc, cc, method, opts...)
+		}
+
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			l.clientRPCLogEvent(log.Ctx(ctx), method, err, time.Since(start)).Msg("finished client streaming call")
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, serverStreams: desc.ServerStreams, finish: func(err error) {
+			l.clientRPCLogEvent(log.Ctx(ctx), method, err, time.Since(start)).Msg("finished client streaming call")
+		}}, nil
+	}
+}
+
+// outgoingTraceContext returns a copy of ctx with the trace headers in the outgoing metadata,
+// if ctx has the trace and the outgoing metadata doesn't have the headers.
+func outgoingTraceContext(ctx context.Context) context.Context {
+	cloudTraceContext, traceparent, ok := outgoingTraceHeaders(ctx)
+	if !ok {
+		return ctx
+	}
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	var kv []string
+	if key := strings.ToLower(CloudTraceContextHeader); len(md.Get(key)) == 0 {
+		kv = append(kv, key, cloudTraceContext)
+	}
+	if key := strings.ToLower(TraceparentHeader); len(md.Get(key)) == 0 && traceparent != "" {
+		kv = append(kv, key, traceparent)
+	}
+	if len(kv) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// clientStream wraps grpc.ClientStream to call finish when the stream is finished.
+type clientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	once          sync.Once
+	finish        func(err error)
+}
+
+// RecvMsg calls the underlying RecvMsg, and finish if the stream is finished.
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF, err == nil && !s.serverStreams:
+		// The stream without server streaming is finished by the single response.
+		s.once.Do(func() { s.finish(nil) })
+	case err != nil:
+		s.once.Do(func() { s.finish(err) })
+	}
+	return err
+}
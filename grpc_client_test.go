@@ -0,0 +1,94 @@
+package crzerolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type clientRPCLogEntry struct {
+	Severity string `json:"severity"`
+	Trace    string `json:"logging.googleapis.com/trace"`
+	Method   string `json:"method"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+func TestTraceClientInterceptor(t *testing.T) {
+	logger := New(Config{ProjectID: "myproject", ClientLog: true})
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	var got metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		got, _ = metadata.FromOutgoingContext(ctx)
+		return status.Error(codes.NotFound, "not found")
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		err := logger.TraceClientInterceptor()(ctx, "/Backend/Get", nil, nil, nil, invoker)
+		return nil, err
+	}
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("x-cloud-trace-context", "0123456789abcdef0123456789abcdef/123;o=1"))
+	logger.InjectLoggerInterceptor(&rootLogger)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/Frontend/Get"}, handler)
+
+	wantCloudTrace := regexp.MustCompile(`^0123456789abcdef0123456789abcdef/\d+;o=1$`)
+	if v := got.Get("x-cloud-trace-context"); len(v) != 1 || !wantCloudTrace.MatchString(v[0]) {
+		t.Errorf("x-cloud-trace-context = %q, want to match %s", v, wantCloudTrace)
+	}
+	wantTraceparent := regexp.MustCompile(`^00-0123456789abcdef0123456789abcdef-[0-9a-f]{16}-01$`)
+	if v := got.Get("traceparent"); len(v) != 1 || !wantTraceparent.MatchString(v[0]) {
+		t.Errorf("traceparent = %q, want to match %s", v, wantTraceparent)
+	}
+
+	var entry clientRPCLogEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := clientRPCLogEntry{
+		Severity: "WARNING",
+		Trace:    "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+		Method:   "/Backend/Get",
+		Code:     "NotFound",
+		Message:  "finished client unary call",
+	}
+	if diff := cmp.Diff(want, entry); diff != "" {
+		t.Errorf("Log output diff: %s", diff)
+	}
+}
+
+func TestTraceClientInterceptorKeepsMetadata(t *testing.T) {
+	logger := New(Config{ProjectID: "myproject"})
+	rootLogger := logger.RootLogger(&bytes.Buffer{})
+
+	var got metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		got, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		ctx = metadata.AppendToOutgoingContext(ctx, "traceparent", "00-11111111111111111111111111111111-1111111111111111-01")
+		return nil, logger.TraceClientInterceptor()(ctx, "/Backend/Get", nil, nil, nil, invoker)
+	}
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("x-cloud-trace-context", "0123456789abcdef0123456789abcdef/123;o=1"))
+	logger.InjectLoggerInterceptor(&rootLogger)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/Frontend/Get"}, handler)
+
+	want := []string{"00-11111111111111111111111111111111-1111111111111111-01"}
+	if diff := cmp.Diff(want, got.Get("traceparent")); diff != "" {
+		t.Errorf("traceparent diff: %s", diff)
+	}
+	if v := got.Get("x-cloud-trace-context"); len(v) != 1 {
+		t.Errorf("x-cloud-trace-context = %q, want a value", v)
+	}
+}
@@ -4,26 +4,31 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"net"
 	"testing"
 
+	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 func TestInjectLoggerInterceptor(t *testing.T) {
 	tests := []struct {
 		desc    string
-		md metadata.MD
+		md      metadata.MD
 		handler func(context.Context, interface{}) (interface{}, error)
-		want logEntry
+		want    logEntry
 	}{
 		{
 			desc: "With x-cloud-trace-context",
-			md: metadata.Pairs("x-cloud-trace-context", "0123456789abcdef0123456789abcdef/123;o=1"),
+			md:   metadata.Pairs("x-cloud-trace-context", "0123456789abcdef0123456789abcdef/123;o=1"),
 			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
 				logger := log.Ctx(ctx)
 				logger.Debug().Msg("hi") // Debug log is ignored
@@ -38,13 +43,37 @@ func TestInjectLoggerInterceptor(t *testing.T) {
 					Line:     "ignore",
 					Function: "ignore",
 				},
-				Trace:   "projects/myproject/traces/0123456789abcdef0123456789abcdef",
-				Message: "hello",
+				Trace:        "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				SpanID:       "000000000000007b",
+				TraceSampled: true,
+				Message:      "hello",
+			},
+		},
+		{
+			desc: "With traceparent",
+			md:   metadata.Pairs("traceparent", "00-0123456789abcdef0123456789abcdef-000000000000007b-01"),
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				logger := log.Ctx(ctx)
+				logger.Info().Msg("hello")
+				return nil, nil
+			},
+			want: logEntry{
+				Time:     "ignore",
+				Severity: "INFO",
+				SourceLocation: sourceLocation{
+					File:     "grpc_test.go",
+					Line:     "ignore",
+					Function: "ignore",
+				},
+				Trace:        "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				SpanID:       "000000000000007b",
+				TraceSampled: true,
+				Message:      "hello",
 			},
 		},
 		{
 			desc: "Without x-cloud-trace-context",
-			md: metadata.New(nil),
+			md:   metadata.New(nil),
 			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
 				logger := log.Ctx(ctx)
 				logger.Debug().Msg("hi") // Debug log is ignored
@@ -65,20 +94,132 @@ func TestInjectLoggerInterceptor(t *testing.T) {
 		},
 	}
 
+	useDefaultLogger(t, "myproject")
+	interceptors := []struct {
+		desc        string
+		interceptor func(buf *bytes.Buffer) grpc.UnaryServerInterceptor
+	}{
+		{
+			desc: "package-level",
+			interceptor: func(buf *bytes.Buffer) grpc.UnaryServerInterceptor {
+				rootLogger := zerolog.New(buf)
+				return InjectLoggerInterceptor(&rootLogger)
+			},
+		},
+		{
+			desc: "New",
+			interceptor: func(buf *bytes.Buffer) grpc.UnaryServerInterceptor {
+				logger := New(Config{ProjectID: "myproject"})
+				rootLogger := logger.RootLogger(buf)
+				return logger.InjectLoggerInterceptor(&rootLogger)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, in := range interceptors {
+			buf := &bytes.Buffer{}
+			zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+			unaryInfo := &grpc.UnaryServerInfo{
+				FullMethod: "TestService.TestMethod",
+			}
+
+			ctx := context.Background()
+			ctx = metadata.NewIncomingContext(ctx, tt.md)
+			interceptor := in.interceptor(buf)
+			interceptor(ctx, nil, unaryInfo, tt.handler)
+
+			var got logEntry
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			opt := cmpopts.IgnoreFields(logEntry{}, "Time", "SourceLocation.Line", "SourceLocation.Function")
+			if diff := cmp.Diff(tt.want, got, opt); diff != "" {
+				t.Errorf("%s (%s): Log output diff: %s", tt.desc, in.desc, diff)
+			}
+		}
+	}
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestInjectLoggerStreamInterceptor(t *testing.T) {
+	tests := []struct {
+		desc    string
+		md      metadata.MD
+		handler func(interface{}, grpc.ServerStream) error
+		want    logEntry
+	}{
+		{
+			desc: "With x-cloud-trace-context",
+			md:   metadata.Pairs("x-cloud-trace-context", "0123456789abcdef0123456789abcdef/123;o=1"),
+			handler: func(srv interface{}, ss grpc.ServerStream) error {
+				logger := log.Ctx(ss.Context())
+				logger.Debug().Msg("hi") // Debug log is ignored
+				logger.Info().Msg("hello")
+				return nil
+			},
+			want: logEntry{
+				Time:     "ignore",
+				Severity: "INFO",
+				SourceLocation: sourceLocation{
+					File:     "grpc_test.go",
+					Line:     "ignore",
+					Function: "ignore",
+				},
+				Trace:        "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				SpanID:       "000000000000007b",
+				TraceSampled: true,
+				Message:      "hello",
+			},
+		},
+		{
+			desc: "Without x-cloud-trace-context",
+			md:   metadata.New(nil),
+			handler: func(srv interface{}, ss grpc.ServerStream) error {
+				logger := log.Ctx(ss.Context())
+				logger.Debug().Msg("hi") // Debug log is ignored
+				logger.Info().Msg("hello")
+				return nil
+			},
+			want: logEntry{
+				Time:     "ignore",
+				Severity: "INFO",
+				SourceLocation: sourceLocation{
+					File:     "grpc_test.go",
+					Line:     "ignore",
+					Function: "ignore",
+				},
+				Trace:   "",
+				Message: "hello",
+			},
+		},
+	}
+
 	for _, tt := range tests {
-		projectID = "myproject"
+		logger := New(Config{ProjectID: "myproject"})
 		buf := &bytes.Buffer{}
-		rootLogger := zerolog.New(buf)
+		rootLogger := logger.RootLogger(buf)
 		zerolog.SetGlobalLevel(zerolog.InfoLevel)
 
-		unaryInfo := &grpc.UnaryServerInfo{
-			FullMethod: "TestService.TestMethod",
+		streamInfo := &grpc.StreamServerInfo{
+			FullMethod:     "TestService.TestStream",
+			IsServerStream: true,
 		}
 
 		ctx := context.Background()
 		ctx = metadata.NewIncomingContext(ctx, tt.md)
-		interceptor := InjectLoggerInterceptor(&rootLogger)
-		interceptor(ctx, nil, unaryInfo, tt.handler)
+		interceptor := logger.InjectLoggerStreamInterceptor(&rootLogger)
+		interceptor(nil, &testServerStream{ctx: ctx}, streamInfo, tt.handler)
 
 		var got logEntry
 		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
@@ -91,3 +232,129 @@ func TestInjectLoggerInterceptor(t *testing.T) {
 		}
 	}
 }
+
+type rpcLogEntry struct {
+	Severity     string `json:"severity"`
+	Trace        string `json:"logging.googleapis.com/trace"`
+	Method       string `json:"method"`
+	Code         string `json:"code"`
+	Duration     string `json:"duration"`
+	PeerAddress  string `json:"peerAddress"`
+	RequestSize  int    `json:"requestSize"`
+	ResponseSize int    `json:"responseSize"`
+	Error        string `json:"error"`
+	Message      string `json:"message"`
+}
+
+func TestInjectLoggerInterceptorRPCLog(t *testing.T) {
+	tests := []struct {
+		desc    string
+		config  Config
+		handler func(context.Context, interface{}) (interface{}, error)
+		want    rpcLogEntry
+	}{
+		{
+			desc:   "OK",
+			config: Config{ProjectID: "myproject", RPCLog: true},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return &wrappers.StringValue{Value: "hello!"}, nil
+			},
+			want: rpcLogEntry{
+				Severity:     "INFO",
+				Trace:        "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				Method:       "TestService.TestMethod",
+				Code:         "OK",
+				Duration:     "ignore",
+				PeerAddress:  "192.0.2.1:1234",
+				RequestSize:  7,
+				ResponseSize: 8,
+				Message:      "finished unary call",
+			},
+		},
+		{
+			desc:   "NotFound",
+			config: Config{ProjectID: "myproject", RPCLog: true},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, status.Error(codes.NotFound, "not found")
+			},
+			want: rpcLogEntry{
+				Severity:    "WARNING",
+				Trace:       "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				Method:      "TestService.TestMethod",
+				Code:        "NotFound",
+				Duration:    "ignore",
+				PeerAddress: "192.0.2.1:1234",
+				RequestSize: 7,
+				Error:       "rpc error: code = NotFound desc = not found",
+				Message:     "finished unary call",
+			},
+		},
+		{
+			desc:   "Internal",
+			config: Config{ProjectID: "myproject", RPCLog: true},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, status.Error(codes.Internal, "internal")
+			},
+			want: rpcLogEntry{
+				Severity:    "ERROR",
+				Trace:       "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				Method:      "TestService.TestMethod",
+				Code:        "Internal",
+				Duration:    "ignore",
+				PeerAddress: "192.0.2.1:1234",
+				RequestSize: 7,
+				Error:       "rpc error: code = Internal desc = internal",
+				Message:     "finished unary call",
+			},
+		},
+		{
+			desc: "Custom level func",
+			config: Config{
+				ProjectID:       "myproject",
+				RPCLog:          true,
+				RPCLogLevelFunc: func(codes.Code) zerolog.Level { return zerolog.DebugLevel },
+			},
+			handler: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, status.Error(codes.Internal, "internal")
+			},
+			want: rpcLogEntry{
+				Severity:    "DEBUG",
+				Trace:       "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				Method:      "TestService.TestMethod",
+				Code:        "Internal",
+				Duration:    "ignore",
+				PeerAddress: "192.0.2.1:1234",
+				RequestSize: 7,
+				Error:       "rpc error: code = Internal desc = internal",
+				Message:     "finished unary call",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		logger := New(tt.config)
+		buf := &bytes.Buffer{}
+		rootLogger := logger.RootLogger(buf)
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+
+		unaryInfo := &grpc.UnaryServerInfo{
+			FullMethod: "TestService.TestMethod",
+		}
+
+		ctx := context.Background()
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-cloud-trace-context", "0123456789abcdef0123456789abcdef/123;o=1"))
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}})
+		interceptor := logger.InjectLoggerInterceptor(&rootLogger)
+		interceptor(ctx, &wrappers.StringValue{Value: "hello"}, unaryInfo, tt.handler)
+
+		var got rpcLogEntry
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		opt := cmpopts.IgnoreFields(rpcLogEntry{}, "Duration")
+		if diff := cmp.Diff(tt.want, got, opt); diff != "" {
+			t.Errorf("%s: Log output diff: %s", tt.desc, diff)
+		}
+	}
+}
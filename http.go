@@ -1,41 +1,238 @@
 package crzerolog
 
 import (
-	"fmt"
+	"bufio"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
 // middleware implements http.Handler interface.
 type middleware struct {
+	logger     *Logger
 	rootLogger *zerolog.Logger
 	next       http.Handler
 }
 
 // InjectLogger returns an HTTP middleware for injecting zerolog.Logger to the request context.
+// It sets zerolog package-level variables for Cloud Logging on first call.
 func InjectLogger(rootLogger *zerolog.Logger) func(http.Handler) http.Handler {
+	return std().InjectLogger(rootLogger)
+}
+
+// InjectLogger returns an HTTP middleware for injecting zerolog.Logger to the request context.
+func (l *Logger) InjectLogger(rootLogger *zerolog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		return &middleware{rootLogger, next}
+		return &middleware{l, l.injectedRootLogger(rootLogger), next}
 	}
 }
 
 // ServeHTTP injects zerolog.Logger to the http context and calls the next handler.
 func (m *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	ctx := m.rootLogger.With().Timestamp().Logger().Hook(sourceLocationHook).WithContext(r.Context())
+	ctx, logger := m.logger.injectLogger(r.Context(), m.rootLogger, r.Header.Get)
 	r = r.WithContext(ctx)
+	for key, value := range m.logger.responseHeader(ctx) {
+		w.Header().Set(key, value)
+	}
 
-	traceID, _ := traceContextFromHeader(r.Header.Get("X-Cloud-Trace-Context"))
-	if traceID == "" {
+	if !m.logger.config.RequestLog && !m.logger.config.RecoverPanic {
 		m.next.ServeHTTP(w, r)
 		return
 	}
-	trace := fmt.Sprintf("projects/%s/traces/%s", projectID, traceID)
 
-	log.Ctx(ctx).UpdateContext(func(c zerolog.Context) zerolog.Context {
-		return c.Str("logging.googleapis.com/trace", trace)
-	})
+	start := time.Now()
+	rw, wrapped := wrapResponseWriter(w)
+	m.serveNext(wrapped, rw, r, &logger)
+	if m.logger.config.RequestLog {
+		writeRequestLog(&logger, r, rw.status, rw.size, time.Since(start))
+	}
+}
 
+// serveNext calls the next handler, recovering from panic if configured.
+func (m *middleware) serveNext(w http.ResponseWriter, rw *responseWriter, r *http.Request, logger *zerolog.Logger) {
+	if m.logger.config.RecoverPanic {
+		defer m.logger.recoverHTTP(logger, w, rw, r)
+	}
 	m.next.ServeHTTP(w, r)
 }
+
+// writeRequestLog writes a log entry with the httpRequest field.
+// See: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
+func writeRequestLog(logger *zerolog.Logger, r *http.Request, status int, size int64, latency time.Duration) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	var event *zerolog.Event
+	switch {
+	case status >= 500:
+		event = logger.Error()
+	case status >= 400:
+		event = logger.Warn()
+	default:
+		event = logger.Info()
+	}
+
+	req := zerolog.Dict().
+		Str("requestMethod", r.Method).
+		Str("requestUrl", requestURL(r)).
+		Int("status", status).
+		Str("responseSize", strconv.FormatInt(size, 10)).
+		Str("latency", formatDuration(latency)).
+		Str("userAgent", r.UserAgent()).
+		Str("remoteIp", remoteIP(r)).
+		Str("referer", r.Referer()).
+		Str("protocol", r.Proto)
+	if r.ContentLength > 0 {
+		req = req.Str("requestSize", strconv.FormatInt(r.ContentLength, 10))
+	}
+	event.Dict("httpRequest", req).Send()
+}
+
+// requestURL returns the absolute URL of the request.
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// remoteIP returns the IP address of the client.
+func remoteIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		return strings.TrimSpace(strings.Split(xff, ",")[0])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// formatDuration formats d as google.protobuf.Duration in JSON, e.g. "0.123s".
+func formatDuration(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
+
+// responseWriter wraps http.ResponseWriter to capture the status code and the response size.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+// WriteHeader records the status code and calls the underlying WriteHeader.
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the response size and calls the underlying Write.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// wrapResponseWriter wraps w with responseWriter.
+// The returned http.ResponseWriter implements http.Flusher, http.Hijacker and http.Pusher
+// only if w implements them.
+func wrapResponseWriter(w http.ResponseWriter) (*responseWriter, http.ResponseWriter) {
+	rw := &responseWriter{ResponseWriter: w}
+	f, isFlusher := w.(http.Flusher)
+	h, isHijacker := w.(http.Hijacker)
+	p, isPusher := w.(http.Pusher)
+
+	switch {
+	case isFlusher && isHijacker && isPusher:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{rw, flusher{rw, f}, hijacker{rw, h}, p}
+	case isFlusher && isHijacker:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Hijacker
+		}{rw, flusher{rw, f}, hijacker{rw, h}}
+	case isFlusher && isPusher:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+			http.Pusher
+		}{rw, flusher{rw, f}, p}
+	case isHijacker && isPusher:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+			http.Pusher
+		}{rw, hijacker{rw, h}, p}
+	case isFlusher:
+		return rw, struct {
+			*responseWriter
+			http.Flusher
+		}{rw, flusher{rw, f}}
+	case isHijacker:
+		return rw, struct {
+			*responseWriter
+			http.Hijacker
+		}{rw, hijacker{rw, h}}
+	case isPusher:
+		return rw, struct {
+			*responseWriter
+			http.Pusher
+		}{rw, p}
+	default:
+		return rw, rw
+	}
+}
+
+// flusher implements http.Flusher interface.
+type flusher struct {
+	rw *responseWriter
+	f  http.Flusher
+}
+
+// Flush records the implicit status code and calls the underlying Flush.
+func (f flusher) Flush() {
+	if f.rw.status == 0 {
+		f.rw.status = http.StatusOK
+	}
+	f.f.Flush()
+}
+
+// hijacker implements http.Hijacker interface.
+type hijacker struct {
+	rw *responseWriter
+	h  http.Hijacker
+}
+
+// Hijack calls the underlying Hijack, and records 101 Switching Protocols as the status code
+// if the connection is taken over before any response is written, e.g. for WebSocket.
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := h.h.Hijack()
+	if err == nil && h.rw.status == 0 {
+		h.rw.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
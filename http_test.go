@@ -1,8 +1,10 @@
 package crzerolog
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -18,6 +20,8 @@ type logEntry struct {
 	Severity       string         `json:"severity"`
 	SourceLocation sourceLocation `json:"logging.googleapis.com/sourceLocation"`
 	Trace          string         `json:"logging.googleapis.com/trace"`
+	SpanID         string         `json:"logging.googleapis.com/spanId"`
+	TraceSampled   bool           `json:"logging.googleapis.com/trace_sampled"`
 	Message        string         `json:"message"`
 }
 
@@ -56,8 +60,38 @@ func TestInjectLogger(t *testing.T) {
 					Line:     "ignore",
 					Function: "ignore",
 				},
-				Trace:   "projects/myproject/traces/0123456789abcdef0123456789abcdef",
-				Message: "hello",
+				Trace:        "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				SpanID:       "000000000000007b",
+				TraceSampled: true,
+				Message:      "hello",
+			},
+		},
+		{
+			desc: "With traceparent",
+			requestFunc: func() *http.Request {
+				req, err := http.NewRequest("GET", "/", nil)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				req.Header.Add("traceparent", "00-0123456789abcdef0123456789abcdef-000000000000007b-01")
+				return req
+			},
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger := log.Ctx(r.Context())
+				logger.Info().Msg("hello")
+			}),
+			want: logEntry{
+				Time:     "ignore",
+				Severity: "INFO",
+				SourceLocation: sourceLocation{
+					File:     "http_test.go",
+					Line:     "ignore",
+					Function: "ignore",
+				},
+				Trace:        "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				SpanID:       "000000000000007b",
+				TraceSampled: true,
+				Message:      "hello",
 			},
 		},
 		{
@@ -88,23 +122,215 @@ func TestInjectLogger(t *testing.T) {
 		},
 	}
 
+	useDefaultLogger(t, "myproject")
+	injectors := []struct {
+		desc     string
+		injector func(buf *bytes.Buffer) func(http.Handler) http.Handler
+	}{
+		{
+			desc: "package-level",
+			injector: func(buf *bytes.Buffer) func(http.Handler) http.Handler {
+				rootLogger := zerolog.New(buf)
+				return InjectLogger(&rootLogger)
+			},
+		},
+		{
+			desc: "New",
+			injector: func(buf *bytes.Buffer) func(http.Handler) http.Handler {
+				logger := New(Config{ProjectID: "myproject"})
+				rootLogger := logger.RootLogger(buf)
+				return logger.InjectLogger(&rootLogger)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, in := range injectors {
+			buf := &bytes.Buffer{}
+			zerolog.SetGlobalLevel(zerolog.InfoLevel)
+			resprec := httptest.NewRecorder()
+
+			in.injector(buf)(tt.handler).ServeHTTP(resprec, tt.requestFunc())
+
+			var got logEntry
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			opt := cmpopts.IgnoreFields(logEntry{}, "Time", "SourceLocation.Line", "SourceLocation.Function")
+			if diff := cmp.Diff(tt.want, got, opt); diff != "" {
+				t.Errorf("%s (%s): Log output diff: %s", tt.desc, in.desc, diff)
+			}
+		}
+	}
+}
+
+type requestLogEntry struct {
+	Severity    string      `json:"severity"`
+	Trace       string      `json:"logging.googleapis.com/trace"`
+	HTTPRequest httpRequest `json:"httpRequest"`
+}
+
+type httpRequest struct {
+	RequestMethod string `json:"requestMethod"`
+	RequestURL    string `json:"requestUrl"`
+	RequestSize   string `json:"requestSize"`
+	Status        int    `json:"status"`
+	ResponseSize  string `json:"responseSize"`
+	UserAgent     string `json:"userAgent"`
+	RemoteIP      string `json:"remoteIp"`
+	Referer       string `json:"referer"`
+	Latency       string `json:"latency"`
+	Protocol      string `json:"protocol"`
+}
+
+func TestInjectLoggerRequestLog(t *testing.T) {
+	tests := []struct {
+		desc    string
+		handler http.Handler
+		want    requestLogEntry
+	}{
+		{
+			desc: "OK",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("hello"))
+			}),
+			want: requestLogEntry{
+				Severity: "INFO",
+				Trace:    "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				HTTPRequest: httpRequest{
+					RequestMethod: "GET",
+					RequestURL:    "https://example.com/foo?bar=baz",
+					Status:        200,
+					ResponseSize:  "5",
+					UserAgent:     "test-agent",
+					RemoteIP:      "192.0.2.1",
+					Referer:       "https://example.com/",
+					Latency:       "ignore",
+					Protocol:      "HTTP/1.1",
+				},
+			},
+		},
+		{
+			desc: "Not Found",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			}),
+			want: requestLogEntry{
+				Severity: "WARNING",
+				Trace:    "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				HTTPRequest: httpRequest{
+					RequestMethod: "GET",
+					RequestURL:    "https://example.com/foo?bar=baz",
+					Status:        404,
+					ResponseSize:  "19",
+					UserAgent:     "test-agent",
+					RemoteIP:      "192.0.2.1",
+					Referer:       "https://example.com/",
+					Latency:       "ignore",
+					Protocol:      "HTTP/1.1",
+				},
+			},
+		},
+		{
+			desc: "Internal Server Error",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}),
+			want: requestLogEntry{
+				Severity: "ERROR",
+				Trace:    "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				HTTPRequest: httpRequest{
+					RequestMethod: "GET",
+					RequestURL:    "https://example.com/foo?bar=baz",
+					Status:        500,
+					ResponseSize:  "0",
+					UserAgent:     "test-agent",
+					RemoteIP:      "192.0.2.1",
+					Referer:       "https://example.com/",
+					Latency:       "ignore",
+					Protocol:      "HTTP/1.1",
+				},
+			},
+		},
+	}
+
 	for _, tt := range tests {
-		projectID = "myproject"
+		logger := New(Config{ProjectID: "myproject", RequestLog: true})
 		buf := &bytes.Buffer{}
-		rootLogger := zerolog.New(buf)
+		rootLogger := logger.RootLogger(buf)
 		zerolog.SetGlobalLevel(zerolog.InfoLevel)
 		resprec := httptest.NewRecorder()
 
-		InjectLogger(&rootLogger)(tt.handler).ServeHTTP(resprec, tt.requestFunc())
+		req := httptest.NewRequest("GET", "https://example.com/foo?bar=baz", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Add("User-Agent", "test-agent")
+		req.Header.Add("Referer", "https://example.com/")
+		req.Header.Add("X-Cloud-Trace-Context", "0123456789abcdef0123456789abcdef/123;o=1")
+		logger.InjectLogger(&rootLogger)(tt.handler).ServeHTTP(resprec, req)
 
-		var got logEntry
+		var got requestLogEntry
 		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
 
-		opt := cmpopts.IgnoreFields(logEntry{}, "Time", "SourceLocation.Line", "SourceLocation.Function")
+		opt := cmpopts.IgnoreFields(requestLogEntry{}, "HTTPRequest.Latency")
 		if diff := cmp.Diff(tt.want, got, opt); diff != "" {
 			t.Errorf("%s: Log output diff: %s", tt.desc, diff)
 		}
 	}
 }
+
+func TestInjectLoggerRequestLogHijacked(t *testing.T) {
+	logger := New(Config{ProjectID: "myproject", RequestLog: true})
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+	logger.InjectLogger(&rootLogger)(handler).ServeHTTP(&hijackRecorder{httptest.NewRecorder()}, httptest.NewRequest("GET", "/ws", nil))
+
+	var got requestLogEntry
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.HTTPRequest.Status != http.StatusSwitchingProtocols {
+		t.Errorf("status = %d, want = %d", got.HTTPRequest.Status, http.StatusSwitchingProtocols)
+	}
+}
+
+type hijackRecorder struct {
+	http.ResponseWriter
+}
+
+func (r *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func TestWrapResponseWriter(t *testing.T) {
+	for _, tt := range []struct {
+		desc         string
+		w            http.ResponseWriter
+		wantFlusher  bool
+		wantHijacker bool
+	}{
+		{"Flusher", httptest.NewRecorder(), true, false},
+		{"Hijacker", &hijackRecorder{httptest.NewRecorder()}, false, true},
+		{"Neither", struct{ http.ResponseWriter }{httptest.NewRecorder()}, false, false},
+	} {
+		_, w := wrapResponseWriter(tt.w)
+		if _, ok := w.(http.Flusher); ok != tt.wantFlusher {
+			t.Errorf("%s: http.Flusher = %v, want = %v", tt.desc, ok, tt.wantFlusher)
+		}
+		if _, ok := w.(http.Hijacker); ok != tt.wantHijacker {
+			t.Errorf("%s: http.Hijacker = %v, want = %v", tt.desc, ok, tt.wantHijacker)
+		}
+		if _, ok := w.(http.Pusher); ok {
+			t.Errorf("%s: http.Pusher = %v, want = %v", tt.desc, ok, false)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package crzerolog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// JobLogger returns a new zerolog.Logger writing to w for Cloud Run Jobs, which have no incoming request.
+// The returned logger adds the job, execution, task index and task attempt as logging.googleapis.com/labels,
+// and the trace generated from the execution name so that the logs of all tasks in the execution are grouped.
+func (l *Logger) JobLogger(w io.Writer) zerolog.Logger {
+	logger := l.rootLogger(w, jobLabels()).Hook(l.timestampHook())
+	l.warnProjectID(&logger)
+	if traceID := jobTraceID(os.Getenv("CLOUD_RUN_EXECUTION")); traceID != "" {
+		logger = l.traceFields(logger.With(), traceID, "", false).Logger()
+	}
+	return l.callSiteHooks(logger)
+}
+
+// jobTraceID returns the trace ID derived from the execution name, or empty string if execution is empty.
+func jobTraceID(execution string) string {
+	if execution == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(execution))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,60 @@
+package crzerolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+type jobLogEntry struct {
+	Severity       string            `json:"severity"`
+	Time           string            `json:"time"`
+	SourceLocation sourceLocation    `json:"logging.googleapis.com/sourceLocation"`
+	Labels         map[string]string `json:"logging.googleapis.com/labels"`
+	Trace          string            `json:"logging.googleapis.com/trace"`
+	Message        string            `json:"message"`
+}
+
+func TestJobLogger(t *testing.T) {
+	t.Setenv("CLOUD_RUN_JOB", "myjob")
+	t.Setenv("CLOUD_RUN_EXECUTION", "myjob-abcde")
+	t.Setenv("CLOUD_RUN_TASK_INDEX", "3")
+	t.Setenv("CLOUD_RUN_TASK_ATTEMPT", "1")
+
+	buf := &bytes.Buffer{}
+	logger := New(Config{ProjectID: "myproject"}).JobLogger(buf)
+	logger.Info().Msg("hello")
+
+	var got jobLogEntry
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := jobLogEntry{
+		Severity: "INFO",
+		Time:     "ignore",
+		SourceLocation: sourceLocation{
+			File:     "job_test.go",
+			Line:     "ignore",
+			Function: "ignore",
+		},
+		Labels: map[string]string{
+			"job_name":       "myjob",
+			"execution_name": "myjob-abcde",
+			"task_index":     "3",
+			"task_attempt":   "1",
+		},
+		Trace:   "projects/myproject/traces/" + jobTraceID("myjob-abcde"),
+		Message: "hello",
+	}
+	opt := cmpopts.IgnoreFields(jobLogEntry{}, "Time", "SourceLocation.Line", "SourceLocation.Function")
+	if diff := cmp.Diff(want, got, opt); diff != "" {
+		t.Errorf("Log output diff: %s", diff)
+	}
+	if len(jobTraceID("myjob-abcde")) != 32 {
+		t.Errorf("Trace ID must be 32-character hexadecimal: %q", jobTraceID("myjob-abcde"))
+	}
+}
@@ -0,0 +1,107 @@
+package crzerolog
+
+import (
+	"context"
+	"sort"
+
+	"github.com/rs/zerolog"
+)
+
+// labelsKey is the context key for the labels added by WithLabels.
+type labelsKey struct{}
+
+// WithLabels returns a copy of ctx with the logger which adds labels to logging.googleapis.com/labels.
+// The labels are merged with the labels already in ctx and the labels of the root logger,
+// and the new labels take precedence over existing ones with the same key.
+//
+// The logger in ctx must be the one injected by the injectors, or derived from the logger returned by Logger.RootLogger.
+func WithLabels(ctx context.Context, labels map[string]string) context.Context {
+	merged := map[string]string{}
+	for k, v := range labelsFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	return withEventContext(context.WithValue(ctx, labelsKey{}, merged))
+}
+
+func labelsFromContext(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(labelsKey{}).(map[string]string)
+	return labels
+}
+
+// labelsHook implements zerolog.Hook interface.
+type labelsHook struct {
+	labels map[string]string
+	// resourceLabels returns the labels of the running resource, which are resolved lazily.
+	resourceLabels func() map[string]string
+	serviceContext bool
+}
+
+// Run adds logging.googleapis.com/labels and serviceContext to zerolog.Event.
+func (h *labelsHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if found, ok := e.GetCtx().Value(rootHooksKey{}).(*bool); ok {
+		// The probe event of hasRootHooks.
+		*found = true
+		e.Discard()
+		return
+	}
+	if level == zerolog.Disabled {
+		// Discarded by levelHook.
+		return
+	}
+	labels := mergeLabels(h.resourceLabels(), h.labels, labelsFromContext(e.GetCtx()))
+	if len(labels) > 0 {
+		e.Dict("logging.googleapis.com/labels", labelsDict(labels))
+	}
+
+	if !h.serviceContext {
+		return
+	}
+	if sc := serviceContext(); sc != nil {
+		e.Dict("serviceContext", sc)
+	}
+}
+
+// mergeLabels returns the labels merged in order, where the later ones take precedence.
+// It returns the only non-empty labels as it is without copying.
+func mergeLabels(labelsList ...map[string]string) map[string]string {
+	var merged map[string]string
+	copied := false
+	for _, labels := range labelsList {
+		if len(labels) == 0 {
+			continue
+		}
+		if merged == nil {
+			merged = labels
+			continue
+		}
+		if !copied {
+			m := make(map[string]string, len(merged)+len(labels))
+			for k, v := range merged {
+				m[k] = v
+			}
+			merged, copied = m, true
+		}
+		for k, v := range labels {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// labelsDict returns labels as a dictionary sorted by key.
+func labelsDict(labels map[string]string) *zerolog.Event {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	dict := zerolog.Dict()
+	for _, k := range keys {
+		dict = dict.Str(k, labels[k])
+	}
+	return dict
+}
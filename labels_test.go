@@ -0,0 +1,88 @@
+package crzerolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestWithLabels(t *testing.T) {
+	t.Setenv("K_SERVICE", "myservice")
+	newTestMetadataServer(t, metadataValues(map[string]string{}))
+
+	logger := New(Config{ProjectID: "myproject", Platform: PlatformCloudRun, ResourceLabels: true})
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithLabels(r.Context(), map[string]string{"user": "alice", "tenant": "a"})
+		ctx = WithLabels(ctx, map[string]string{"tenant": "b", "service_name": "overridden"})
+		log.Ctx(ctx).Info().Msg("hello")
+	})
+	logger.InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if n := strings.Count(buf.String(), "logging.googleapis.com/labels"); n != 1 {
+		t.Errorf("labels field is written %d times, want = 1: %s", n, buf.String())
+	}
+
+	var got struct {
+		Labels map[string]string `json:"logging.googleapis.com/labels"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"service_name": "overridden",
+		"user":         "alice",
+		"tenant":       "b",
+	}
+	if diff := cmp.Diff(want, got.Labels); diff != "" {
+		t.Errorf("Labels diff: %s", diff)
+	}
+}
+
+func TestWithLabelsPackageLevel(t *testing.T) {
+	useDefaultLogger(t, "myproject")
+	buf := &bytes.Buffer{}
+	rootLogger := zerolog.New(buf)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithLabels(r.Context(), map[string]string{"tenant": "a"})
+		log.Ctx(ctx).Info().Msg("hello")
+	})
+	InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var got struct {
+		Labels map[string]string `json:"logging.googleapis.com/labels"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(map[string]string{"tenant": "a"}, got.Labels); diff != "" {
+		t.Errorf("Labels diff: %s", diff)
+	}
+}
+
+func TestHasRootHooks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	rootLogger := New(Config{ProjectID: "myproject"}).RootLogger(buf)
+	if !hasRootHooks(&rootLogger) {
+		t.Errorf("hasRootHooks(RootLogger) = false, want = true")
+	}
+	plainLogger := zerolog.New(buf)
+	if hasRootHooks(&plainLogger) {
+		t.Errorf("hasRootHooks(zerolog.New) = true, want = false")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("The probe event is written: %s", buf.String())
+	}
+}
@@ -0,0 +1,283 @@
+package crzerolog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// SetLevel changes the runtime level of the loggers created by RootLogger and JobLogger of l.
+// If pkg is not empty, the level applies only to the log entries written from the package and its subpackages,
+// e.g. "github.com/example/app/db".
+// If ttl is positive, the level is reverted to the previous one after ttl.
+//
+// The runtime level starts at Config.Level. It filters the log entries in addition to the level of each logger
+// and zerolog.GlobalLevel, so it can't be lower than them, e.g. TraceLevel requires zerolog.SetGlobalLevel(zerolog.TraceLevel).
+// The log entries below the runtime level are discarded after they are built, so unlike those below
+// the level of the logger, they cost as much as the written ones except for the output.
+func (l *Logger) SetLevel(pkg string, level zerolog.Level, ttl time.Duration) {
+	l.levels.set(pkg, level, true, ttl)
+}
+
+// ResetLevel removes the runtime level of the package set by SetLevel.
+// If pkg is empty, the runtime level is reset to Config.Level.
+func (l *Logger) ResetLevel(pkg string) {
+	l.levels.set(pkg, l.config.Level, pkg == "", 0)
+}
+
+// Level returns the runtime level set by SetLevel.
+// If pkg is not empty, it returns the level set for the package, or false if not set.
+func (l *Logger) Level(pkg string) (zerolog.Level, bool) {
+	return l.levels.get(pkg)
+}
+
+// levels holds the runtime levels of the loggers.
+// The levels are read on every log entry, so they are read without lock.
+type levels struct {
+	root     atomic.Int32
+	packages atomic.Pointer[map[string]zerolog.Level]
+
+	// mu guards the updates of the levels and pending.
+	mu sync.Mutex
+	// pending has the levels to revert to after TTL, keyed by the package or empty string for the root level.
+	pending map[string]*pendingLevel
+}
+
+// pendingLevel is the level to revert to after TTL.
+type pendingLevel struct {
+	timer *time.Timer
+	level zerolog.Level
+	// ok is false if the package had no level.
+	ok bool
+}
+
+// newLevels returns levels with root as the root level.
+func newLevels(root zerolog.Level) *levels {
+	ls := &levels{pending: map[string]*pendingLevel{}}
+	ls.root.Store(int32(root))
+	ls.packages.Store(&map[string]zerolog.Level{})
+	return ls
+}
+
+// rootLevel returns the root level.
+func (ls *levels) rootLevel() zerolog.Level {
+	return zerolog.Level(ls.root.Load())
+}
+
+// packageLevels returns the levels of the packages, which must not be modified.
+func (ls *levels) packageLevels() map[string]zerolog.Level {
+	return *ls.packages.Load()
+}
+
+// set sets the level of pkg, or removes it if ok is false, and schedules the revert if ttl is positive.
+func (ls *levels) set(pkg string, level zerolog.Level, ok bool, ttl time.Duration) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	prev, prevOK := ls.get(pkg)
+	if p, found := ls.pending[pkg]; found {
+		// Revert to the level before the first pending change.
+		p.timer.Stop()
+		prev, prevOK = p.level, p.ok
+		delete(ls.pending, pkg)
+	}
+	ls.store(pkg, level, ok)
+
+	if ttl > 0 {
+		p := &pendingLevel{level: prev, ok: prevOK}
+		p.timer = time.AfterFunc(ttl, func() {
+			ls.mu.Lock()
+			defer ls.mu.Unlock()
+			// The timer may be fired after it's replaced by another change.
+			if ls.pending[pkg] != p {
+				return
+			}
+			delete(ls.pending, pkg)
+			ls.store(pkg, p.level, p.ok)
+		})
+		ls.pending[pkg] = p
+	}
+}
+
+// get returns the level of pkg, or the root level if pkg is empty.
+func (ls *levels) get(pkg string) (zerolog.Level, bool) {
+	if pkg == "" {
+		return ls.rootLevel(), true
+	}
+	level, ok := ls.packageLevels()[pkg]
+	return level, ok
+}
+
+// store stores the level of pkg, or removes it if ok is false. ls.mu must be held.
+func (ls *levels) store(pkg string, level zerolog.Level, ok bool) {
+	if pkg == "" {
+		ls.root.Store(int32(level))
+		return
+	}
+	packages := map[string]zerolog.Level{}
+	for k, v := range ls.packageLevels() {
+		packages[k] = v
+	}
+	if ok {
+		packages[pkg] = level
+	} else {
+		delete(packages, pkg)
+	}
+	ls.packages.Store(&packages)
+}
+
+// levelHook implements zerolog.Hook interface.
+type levelHook struct {
+	levels *levels
+}
+
+// Run discards the log event below the runtime level.
+// It must be the first hook of the logger, so that the other hooks can skip the discarded event.
+func (h *levelHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level == zerolog.NoLevel || level == zerolog.Disabled {
+		return
+	}
+
+	threshold := h.levels.rootLevel()
+	if packages := h.levels.packageLevels(); len(packages) > 0 {
+		if pkgLevel, ok := packageLevel(packages, callerPackage(e)); ok {
+			threshold = pkgLevel
+		}
+	}
+	if debugLevel, ok := e.GetCtx().Value(debugLevelKey{}).(zerolog.Level); ok && debugLevel < threshold {
+		threshold = debugLevel
+	}
+	if level < threshold {
+		e.Discard()
+	}
+}
+
+// callerPackage returns the package path of the log site.
+func callerPackage(e *zerolog.Event) string {
+	// Skip runtime.Callers and callerPackage in addition to the frames skipped by callerHook.
+	pcs := []uintptr{0}
+	if pc, ok := e.GetCtx().Value(callerPCKey{}).(uintptr); ok {
+		pcs[0] = pc
+	} else if runtime.Callers(CallerSkipFrameCount+2, pcs) == 0 {
+		return ""
+	}
+	// runtime.CallersFrames resolves the inlined frames, e.g. zerolog.Event.Msg inlined into the log site.
+	frame, _ := runtime.CallersFrames(pcs).Next()
+	return packagePath(frame.Function)
+}
+
+// packagePath returns the package path of the function name,
+// e.g. "github.com/example/app/db" for "github.com/example/app/db.(*Client).Query".
+func packagePath(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
+
+// packageLevel returns the level of the longest package in packages which matches pkg or its parent.
+func packageLevel(packages map[string]zerolog.Level, pkg string) (zerolog.Level, bool) {
+	var level zerolog.Level
+	var matched string
+	found := false
+	for name, lvl := range packages {
+		if (pkg == name || strings.HasPrefix(pkg, name+"/")) && len(name) >= len(matched) {
+			level, matched, found = lvl, name, true
+		}
+	}
+	return level, found
+}
+
+// levelRequest is the request to change the runtime level.
+type levelRequest struct {
+	// Package is the package to change the level of, or empty for the root level.
+	Package string `json:"package"`
+	// Level is the level name, e.g. "debug". If empty, the level of the package is reset.
+	Level string `json:"level"`
+	// TTL is the duration to revert the level after, e.g. "10m".
+	TTL string `json:"ttl"`
+}
+
+// levelResponse is the current runtime levels.
+type levelResponse struct {
+	Level    string            `json:"level"`
+	Packages map[string]string `json:"packages"`
+}
+
+// applyLevelRequest changes the runtime level as req.
+func (l *Logger) applyLevelRequest(req levelRequest) error {
+	var ttl time.Duration
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid ttl %q", req.TTL)
+		}
+		ttl = d
+	}
+	if req.Level == "" {
+		if ttl > 0 {
+			return fmt.Errorf("ttl requires level")
+		}
+		l.ResetLevel(req.Package)
+		return nil
+	}
+	level, err := zerolog.ParseLevel(req.Level)
+	if err != nil || level == zerolog.NoLevel {
+		return fmt.Errorf("invalid level %q", req.Level)
+	}
+	l.SetLevel(req.Package, level, ttl)
+	return nil
+}
+
+// levelResponse returns the current runtime levels.
+func (l *Logger) levelResponse() levelResponse {
+	resp := levelResponse{Level: l.levels.rootLevel().String(), Packages: map[string]string{}}
+	packages := l.levels.packageLevels()
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		resp.Packages[name] = packages[name].String()
+	}
+	return resp
+}
+
+// LevelHandler returns an http.Handler to read and change the runtime level by SetLevel.
+// GET returns the current levels, and PUT or POST with JSON body such as
+// {"package": "github.com/example/app/db", "level": "debug", "ttl": "10m"} changes the level.
+// The handler should be mounted on an admin path which is not exposed to the public.
+func (l *Logger) LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var req levelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			if err := l.applyLevelRequest(req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(l.levelResponse())
+	})
+}
@@ -0,0 +1,106 @@
+package crzerolog
+
+import (
+	"context"
+
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RegisterLevelService registers the gRPC admin service to read and change the runtime level by SetLevel.
+// The service is "crzerolog.LevelService" with the following methods, which take and return
+// google.protobuf.Struct with the same fields as the JSON of LevelHandler.
+//
+//	rpc GetLevel(google.protobuf.Struct) returns (google.protobuf.Struct);
+//	rpc SetLevel(google.protobuf.Struct) returns (google.protobuf.Struct);
+//
+// The service should be registered only on a server which is not exposed to the public.
+func (l *Logger) RegisterLevelService(s *grpc.Server) {
+	s.RegisterService(&levelServiceDesc, &levelService{l})
+}
+
+// levelServiceServer is the server API for crzerolog.LevelService.
+type levelServiceServer interface {
+	GetLevel(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	SetLevel(context.Context, *structpb.Struct) (*structpb.Struct, error)
+}
+
+// levelService implements levelServiceServer interface.
+type levelService struct {
+	logger *Logger
+}
+
+// GetLevel returns the current runtime levels.
+func (s *levelService) GetLevel(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	return levelResponseStruct(s.logger.levelResponse()), nil
+}
+
+// SetLevel changes the runtime level and returns the current runtime levels.
+func (s *levelService) SetLevel(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	if err := s.logger.applyLevelRequest(levelRequestFromStruct(req)); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return levelResponseStruct(s.logger.levelResponse()), nil
+}
+
+// levelRequestFromStruct converts google.protobuf.Struct to levelRequest.
+func levelRequestFromStruct(req *structpb.Struct) levelRequest {
+	str := func(key string) string {
+		return req.GetFields()[key].GetStringValue()
+	}
+	return levelRequest{Package: str("package"), Level: str("level"), TTL: str("ttl")}
+}
+
+// levelResponseStruct converts levelResponse to google.protobuf.Struct.
+func levelResponseStruct(resp levelResponse) *structpb.Struct {
+	str := func(s string) *structpb.Value {
+		return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: s}}
+	}
+	packages := &structpb.Struct{Fields: map[string]*structpb.Value{}}
+	for name, level := range resp.Packages {
+		packages.Fields[name] = str(level)
+	}
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		"level":    str(resp.Level),
+		"packages": {Kind: &structpb.Value_StructValue{StructValue: packages}},
+	}}
+}
+
+var levelServiceDesc = grpc.ServiceDesc{
+	ServiceName: "crzerolog.LevelService",
+	HandlerType: (*levelServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetLevel",
+			Handler:    levelServiceHandler("GetLevel", levelServiceServer.GetLevel),
+		},
+		{
+			MethodName: "SetLevel",
+			Handler:    levelServiceHandler("SetLevel", levelServiceServer.SetLevel),
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// levelServiceHandler returns the handler of the method of crzerolog.LevelService.
+func levelServiceHandler(method string, call func(levelServiceServer, context.Context, *structpb.Struct) (*structpb.Struct, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := new(structpb.Struct)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv.(levelServiceServer), ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: "/crzerolog.LevelService/" + method,
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv.(levelServiceServer), ctx, req.(*structpb.Struct))
+		}
+		return interceptor(ctx, in, info, handler)
+	}
+}
@@ -0,0 +1,297 @@
+package crzerolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/google/go-cmp/cmp"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSetLevel(t *testing.T) {
+	tests := []struct {
+		desc      string
+		levels    map[string]zerolog.Level
+		wantDebug bool
+		wantInfo  bool
+	}{
+		{
+			desc:      "Default",
+			levels:    map[string]zerolog.Level{},
+			wantDebug: true,
+			wantInfo:  true,
+		},
+		{
+			desc:      "Root level",
+			levels:    map[string]zerolog.Level{"": zerolog.WarnLevel},
+			wantDebug: false,
+			wantInfo:  false,
+		},
+		{
+			desc:      "Package level",
+			levels:    map[string]zerolog.Level{"": zerolog.WarnLevel, "github.com/yfuruyama/crzerolog": zerolog.InfoLevel},
+			wantDebug: false,
+			wantInfo:  true,
+		},
+		{
+			desc:      "Parent package level",
+			levels:    map[string]zerolog.Level{"": zerolog.WarnLevel, "github.com/yfuruyama": zerolog.DebugLevel},
+			wantDebug: true,
+			wantInfo:  true,
+		},
+		{
+			desc:      "Longest package level",
+			levels:    map[string]zerolog.Level{"github.com/yfuruyama": zerolog.DebugLevel, "github.com/yfuruyama/crzerolog": zerolog.WarnLevel},
+			wantDebug: false,
+			wantInfo:  false,
+		},
+		{
+			desc:      "Other package level",
+			levels:    map[string]zerolog.Level{"": zerolog.WarnLevel, "github.com/yfuruyama/crzerolog/other": zerolog.DebugLevel},
+			wantDebug: false,
+			wantInfo:  false,
+		},
+	}
+
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	defer zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	for _, tt := range tests {
+		logger := New(Config{ProjectID: "myproject"})
+		for pkg, level := range tt.levels {
+			logger.SetLevel(pkg, level, 0)
+		}
+		buf := &bytes.Buffer{}
+		rootLogger := logger.RootLogger(buf)
+
+		rootLogger.Debug().Msg("debug")
+		rootLogger.Info().Msg("info")
+		if got := strings.Contains(buf.String(), `"message":"debug"`); got != tt.wantDebug {
+			t.Errorf("%s: debug log written = %v, want = %v", tt.desc, got, tt.wantDebug)
+		}
+		if got := strings.Contains(buf.String(), `"message":"info"`); got != tt.wantInfo {
+			t.Errorf("%s: info log written = %v, want = %v", tt.desc, got, tt.wantInfo)
+		}
+	}
+}
+
+func TestSetLevelTTL(t *testing.T) {
+	logger := New(Config{ProjectID: "myproject"})
+	logger.SetLevel("", zerolog.WarnLevel, 0)
+	logger.SetLevel("", zerolog.DebugLevel, time.Hour)
+	// The level is reverted to the one before the first pending change.
+	logger.SetLevel("", zerolog.ErrorLevel, 10*time.Millisecond)
+	logger.SetLevel("example.com/db", zerolog.DebugLevel, 10*time.Millisecond)
+
+	if level, _ := logger.Level(""); level != zerolog.ErrorLevel {
+		t.Errorf("Level = %v, want = %v", level, zerolog.ErrorLevel)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		level, _ := logger.Level("")
+		_, ok := logger.Level("example.com/db")
+		if level == zerolog.WarnLevel && !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Level is not reverted: %v, package level set = %v", level, ok)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSetLevelLowerThanConfig(t *testing.T) {
+	// zerolog.GlobalLevel is DebugLevel by default.
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	defer zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	logger := New(Config{ProjectID: "myproject", Level: zerolog.InfoLevel})
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+
+	rootLogger.Debug().Msg("debug")
+	if buf.Len() != 0 {
+		t.Errorf("debug log is written at InfoLevel: %s", buf.String())
+	}
+
+	logger.SetLevel("", zerolog.DebugLevel, 10*time.Millisecond)
+	rootLogger.Debug().Msg("debug")
+	if !strings.Contains(buf.String(), `"message":"debug"`) {
+		t.Errorf("debug log is not written after SetLevel")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if level, _ := logger.Level(""); level == zerolog.InfoLevel {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Level is not reverted to InfoLevel")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	buf.Reset()
+	rootLogger.Debug().Msg("debug")
+	if buf.Len() != 0 {
+		t.Errorf("debug log is written after the level is reverted: %s", buf.String())
+	}
+}
+
+func TestSetLevelWithDebugLog(t *testing.T) {
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	defer zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	logger := New(Config{ProjectID: "myproject", DebugLogHeader: "X-Debug-Log", DebugLogAllowFunc: allowDebugLog})
+	logger.SetLevel("", zerolog.InfoLevel, 0)
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Ctx(r.Context()).Debug().Msg("debug")
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Debug-Log", "1")
+	logger.InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), req)
+	if !strings.Contains(buf.String(), `"message":"debug"`) {
+		t.Errorf("debug log is not written for the request with X-Debug-Log")
+	}
+
+	buf.Reset()
+	logger.InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if buf.Len() != 0 {
+		t.Errorf("debug log is written for the request without X-Debug-Log: %s", buf.String())
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	tests := []struct {
+		desc       string
+		method     string
+		body       string
+		wantStatus int
+		want       levelResponse
+	}{
+		{
+			desc:       "GET",
+			method:     "GET",
+			wantStatus: http.StatusOK,
+			want:       levelResponse{Level: "debug", Packages: map[string]string{}},
+		},
+		{
+			desc:       "PUT root level",
+			method:     "PUT",
+			body:       `{"level": "warn", "ttl": "10m"}`,
+			wantStatus: http.StatusOK,
+			want:       levelResponse{Level: "warn", Packages: map[string]string{}},
+		},
+		{
+			desc:       "POST package level",
+			method:     "POST",
+			body:       `{"package": "example.com/db", "level": "debug"}`,
+			wantStatus: http.StatusOK,
+			want:       levelResponse{Level: "debug", Packages: map[string]string{"example.com/db": "debug"}},
+		},
+		{
+			desc:       "Invalid level",
+			method:     "PUT",
+			body:       `{"level": "verbose"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			desc:       "Invalid ttl",
+			method:     "PUT",
+			body:       `{"level": "debug", "ttl": "forever"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			desc:       "DELETE",
+			method:     "DELETE",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		logger := New(Config{ProjectID: "myproject"})
+		resprec := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, "/admin/level", strings.NewReader(tt.body))
+		logger.LevelHandler().ServeHTTP(resprec, req)
+
+		if resprec.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want = %d", tt.desc, resprec.Code, tt.wantStatus)
+		}
+		if tt.wantStatus != http.StatusOK {
+			continue
+		}
+		var got levelResponse
+		if err := json.Unmarshal(resprec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%s: Unexpected error: %v", tt.desc, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: Response diff: %s", tt.desc, diff)
+		}
+	}
+}
+
+func TestLevelService(t *testing.T) {
+	logger := New(Config{ProjectID: "myproject"})
+	// RegisterService fails if levelService doesn't implement the handler type.
+	logger.RegisterLevelService(grpc.NewServer())
+
+	call := func(method string, req *structpb.Struct) (*structpb.Struct, error) {
+		for _, m := range levelServiceDesc.Methods {
+			if m.MethodName != method {
+				continue
+			}
+			dec := func(in interface{}) error {
+				*in.(*structpb.Struct) = *req
+				return nil
+			}
+			resp, err := m.Handler(&levelService{logger}, context.Background(), dec, nil)
+			if err != nil {
+				return nil, err
+			}
+			return resp.(*structpb.Struct), nil
+		}
+		t.Fatalf("Unknown method: %s", method)
+		return nil, nil
+	}
+	str := func(s string) *structpb.Value {
+		return &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: s}}
+	}
+
+	resp, err := call("SetLevel", &structpb.Struct{Fields: map[string]*structpb.Value{
+		"package": str("example.com/db"),
+		"level":   str("debug"),
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := resp.GetFields()["packages"].GetStructValue().GetFields()["example.com/db"].GetStringValue(); got != "debug" {
+		t.Errorf("package level = %q, want = %q", got, "debug")
+	}
+
+	resp, err = call("GetLevel", &structpb.Struct{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := resp.GetFields()["level"].GetStringValue(); got != "debug" {
+		t.Errorf("level = %q, want = %q", got, "debug")
+	}
+
+	_, err = call("SetLevel", &structpb.Struct{Fields: map[string]*structpb.Value{"level": str("verbose")}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("code = %v, want = %v", status.Code(err), codes.InvalidArgument)
+	}
+}
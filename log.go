@@ -2,78 +2,503 @@
 package crzerolog
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"net/http"
-	"os"
+	"io"
 	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
 )
 
 var (
 	// CallerSkipFrameCount is the number of stack frames to skip to find the caller.
 	CallerSkipFrameCount = 3
 
-	projectID          string
 	sourceLocationHook = &callerHook{}
 	// For trace header, see https://cloud.google.com/trace/docs/troubleshooting#force-trace
-	traceHeaderRegExp = regexp.MustCompile(`^\s*([0-9a-fA-F]+)(?:/(\d+))?(?:;o=[01])?\s*$`)
+	traceHeaderRegExp = regexp.MustCompile(`^\s*([0-9a-fA-F]+)(?:/(\d+))?(?:;o=([01]))?\s*$`)
+	// For traceparent header, see https://www.w3.org/TR/trace-context/#traceparent-header
+	traceparentHeaderRegExp = regexp.MustCompile(`^\s*([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})(-.*)?\s*$`)
+
+	defaultLogger     *Logger
+	defaultLoggerOnce sync.Once
+
+	defaultClientLogger     *Logger
+	defaultClientLoggerOnce sync.Once
 )
 
-func init() {
-	zerolog.TimeFieldFormat = time.RFC3339Nano
-	zerolog.LevelFieldName = "severity"
-	zerolog.LevelFieldMarshalFunc = func(l zerolog.Level) string {
-		// mapping to Cloud Logging LogSeverity
-		// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogSeverity
-		switch l {
-		case zerolog.TraceLevel:
-			return "DEFAULT"
-		case zerolog.DebugLevel:
-			return "DEBUG"
-		case zerolog.InfoLevel:
-			return "INFO"
-		case zerolog.WarnLevel:
-			return "WARNING"
-		case zerolog.ErrorLevel:
-			return "ERROR"
-		case zerolog.FatalLevel:
-			return "CRITICAL"
-		case zerolog.PanicLevel:
-			return "ALERT"
-		case zerolog.NoLevel:
-			return "DEFAULT"
-		default:
-			return "DEFAULT"
+const (
+	// CloudTraceContextHeader is the header name of the Google Cloud trace context.
+	CloudTraceContextHeader = "X-Cloud-Trace-Context"
+	// TraceparentHeader is the header name of the W3C Trace Context.
+	TraceparentHeader = "traceparent"
+)
+
+// Config is the configuration for New.
+type Config struct {
+	// ProjectID is the Google Cloud project ID used for the trace field.
+	// If empty, it is resolved in the background from the metadata server on Google Cloud,
+	// or from GOOGLE_CLOUD_PROJECT, GCLOUD_PROJECT or CLOUDSDK_CORE_PROJECT env var otherwise.
+	ProjectID string
+
+	// FallbackProjectID is the project ID used if ProjectID is empty and can't be resolved.
+	FallbackProjectID string
+
+	// TimeFieldName is the field name of the timestamp. Defaults to "time".
+	TimeFieldName string
+
+	// TimeFieldFormat is the format of the timestamp. Defaults to time.RFC3339Nano.
+	TimeFieldFormat string
+
+	// LevelFieldName is the field name of the level. Defaults to "severity".
+	LevelFieldName string
+
+	// LevelFieldMarshalFunc converts the level to the value of the level field.
+	// Defaults to Severity.
+	LevelFieldMarshalFunc func(zerolog.Level) string
+
+	// Level is the initial runtime level of the loggers created by RootLogger and JobLogger,
+	// which can be changed by SetLevel without restarting. Defaults to zerolog.DebugLevel.
+	// Use it instead of zerolog.SetGlobalLevel or zerolog.Logger.Level, which the runtime level can't lower.
+	Level zerolog.Level
+
+	// TraceHeaders is the list of headers to read the trace context from, in order of precedence.
+	// Supported headers are CloudTraceContextHeader and TraceparentHeader.
+	// Defaults to []string{CloudTraceContextHeader, TraceparentHeader}.
+	TraceHeaders []string
+
+	// GenerateTrace makes the injectors generate a random trace ID and span ID for the request without
+	// any valid trace header, so that the log entries of the request can be grouped.
+	GenerateTrace bool
+
+	// TraceResponseHeader is the response header or metadata key to return the trace ID of the request, e.g. "X-Trace-Id".
+	// If empty, the trace ID is not returned.
+	TraceResponseHeader string
+
+	// RequestID makes the injectors read the request ID from RequestIDHeader, or generate one if not present.
+	// The request ID is added to every log entry of the request as requestId field,
+	// returned in the same response header or metadata, and available by RequestIDFromContext.
+	RequestID bool
+
+	// RequestIDHeader is the request header or metadata key of the request ID.
+	// Defaults to RequestIDHeader.
+	RequestIDHeader string
+
+	// DebugLogHeader is the request header or metadata key to lower the level of the injected logger
+	// to DebugLogLevel for the request, e.g. "X-Debug-Log". It requires DebugLogAllowFunc.
+	// If empty, the header is ignored.
+	// The level of the other requests is not affected. Note that zerolog.GlobalLevel still applies,
+	// so set Level instead, e.g. zerolog.InfoLevel.
+	DebugLogHeader string
+
+	// DebugLogAllowFunc reports whether the value of DebugLogHeader enables the debug log,
+	// e.g. by comparing it with a secret token. If nil, DebugLogHeader is ignored,
+	// so that any client can't enable the debug log.
+	DebugLogAllowFunc func(value string) bool
+
+	// DebugLogSampled enables the debug log for the requests whose trace is sampled.
+	DebugLogSampled bool
+
+	// DebugLogLevel is the level of the injected logger for the request with the debug log.
+	// Defaults to zerolog.DebugLevel.
+	DebugLogLevel zerolog.Level
+
+	// RequestLog makes InjectLogger write a log entry with the httpRequest field on completion of each request.
+	// It's useful on platforms which don't write request logs by themselves, such as GKE or local environment.
+	RequestLog bool
+
+	// RPCLog makes InjectLoggerInterceptor and InjectLoggerStreamInterceptor write a log entry
+	// on completion of each RPC, with the method, status code, duration and peer address.
+	RPCLog bool
+
+	// ClientLog makes Transport and the client interceptors write a log entry on completion of
+	// each outgoing HTTP request or RPC through the logger in the request context.
+	ClientLog bool
+
+	// RPCLogLevelFunc converts the gRPC status code to the level of the RPC log entry.
+	// Defaults to CodeToLevel.
+	RPCLogLevelFunc func(codes.Code) zerolog.Level
+
+	// Platform is the platform where the application is running.
+	// If PlatformUnknown, it is detected by DetectPlatform.
+	Platform Platform
+
+	// ResourceLabels makes RootLogger add the labels of the running resource, such as
+	// the Cloud Run service, revision, configuration and instance ID, as logging.googleapis.com/labels
+	// and serviceContext to every log entry.
+	// The labels from the metadata server are resolved in the background, and the first log entry waits for them.
+	ResourceLabels bool
+
+	// ErrorReporting makes the injected logger write the log entries at ErrorLevel or higher
+	// in Error Reporting format, with the stack trace at the log site.
+	// Use Err to report the stack trace of the error instead.
+	ErrorReporting bool
+
+	// RecoverPanic makes the injectors recover from panic in the handler and log it in Error Reporting format.
+	// The HTTP middleware responds with 500, and the gRPC interceptors return codes.Internal error.
+	RecoverPanic bool
+
+	// TraceFromContext makes the injected logger read the trace from the span in the request context,
+	// instead of the trace headers. It returns false if ctx has no valid span.
+	// For OpenTelemetry, use TraceFromContext of github.com/yfuruyama/crzerolog/otelspan.
+	// The span is resolved at log time, so a log written with zerolog.Event.Ctx(ctx)
+	// is attached to the child span in ctx if any.
+	// The trace headers are used only if the request context has no valid span.
+	TraceFromContext func(ctx context.Context) (TraceContext, bool)
+
+	// Console controls the human-readable console output for local development.
+	// Defaults to ConsoleAuto. It can be overridden by CRZEROLOG_CONSOLE env var, e.g. "true" or "false".
+	Console ConsoleMode
+
+	// SetGlobals makes New apply the above time and level settings to
+	// the zerolog package-level variables, which affects all zerolog loggers in the process.
+	SetGlobals bool
+}
+
+// Logger creates zerolog.Logger for Cloud Logging and injects it to the request context.
+type Logger struct {
+	config Config
+	levels *levels
+
+	labelsOnce sync.Once
+	labels     map[string]string
+
+	projectIDOnce     sync.Once
+	resolvedProjectID string
+	projectIDErr      error
+	warnProjectIDOnce sync.Once
+}
+
+// New returns a Logger configured with config.
+// Unless config.SetGlobals is true, New doesn't modify any zerolog package-level variables.
+func New(config Config) *Logger {
+	if config.TimeFieldName == "" {
+		config.TimeFieldName = "time"
+	}
+	if config.TimeFieldFormat == "" {
+		config.TimeFieldFormat = time.RFC3339Nano
+	}
+	if config.LevelFieldName == "" {
+		config.LevelFieldName = "severity"
+	}
+	if config.LevelFieldMarshalFunc == nil {
+		config.LevelFieldMarshalFunc = Severity
+	}
+	if config.RequestIDHeader == "" {
+		config.RequestIDHeader = RequestIDHeader
+	}
+	if config.RPCLogLevelFunc == nil {
+		config.RPCLogLevelFunc = CodeToLevel
+	}
+	if config.TraceHeaders == nil {
+		config.TraceHeaders = []string{CloudTraceContextHeader, TraceparentHeader}
+	}
+
+	if config.Platform == PlatformUnknown {
+		config.Platform = DetectPlatform()
+	}
+
+	if config.SetGlobals {
+		zerolog.TimeFieldFormat = config.TimeFieldFormat
+		zerolog.TimestampFieldName = config.TimeFieldName
+		zerolog.LevelFieldName = config.LevelFieldName
+		zerolog.LevelFieldMarshalFunc = config.LevelFieldMarshalFunc
+	}
+
+	l := &Logger{config: config, levels: newLevels(config.Level)}
+	if config.ProjectID == "" {
+		// Resolve the project ID in the background, so that the first request doesn't wait for it.
+		go l.projectID()
+	}
+	if config.ResourceLabels {
+		// Resolve the labels in the background, as some of them are read from the metadata server.
+		go l.resourceLabels()
+	}
+	return l
+}
+
+// RootLogger returns a new zerolog.Logger writing to w, whose level field is formatted for Cloud Logging.
+// The returned logger is intended to be passed to InjectLogger or InjectLoggerInterceptor of l.
+// Labels added by WithLabels and operations started by StartOperation are written
+// only by loggers derived from the returned logger.
+func (l *Logger) RootLogger(w io.Writer) zerolog.Logger {
+	return l.rootLogger(w, nil)
+}
+
+// rootLogger returns a new zerolog.Logger writing to w with labels in addition to the resource labels.
+func (l *Logger) rootLogger(w io.Writer, labels map[string]string) zerolog.Logger {
+	if l.useConsole(w) {
+		// zerolog.ConsoleWriter reads the level field written by zerolog as it is.
+		w = l.consoleWriter(w)
+	} else if !l.config.SetGlobals {
+		w = &levelWriter{
+			w:         w,
+			fieldName: l.config.LevelFieldName,
+			marshal:   l.config.LevelFieldMarshalFunc,
 		}
 	}
+	// The logger is at TraceLevel as created by zerolog.New, and levelHook filters the log entries by the runtime level.
+	return zerolog.New(w).Hook(&levelHook{l.levels}, l.labelsHook(labels), &operationHook{})
+}
 
-	if isCloudRun() || isAppEngineSecond() {
-		// For performance, fetching Project ID here only once,
-		// rather than fetching it in every request.
-		id, err := fetchProjectIDFromMetadata()
-		if err != nil {
-			log.Fatalf("Failed to fetch mandatory project ID: %v", err)
+// labelsHook returns the hook to add labels, the resource labels and the labels in the event context.
+func (l *Logger) labelsHook(labels map[string]string) zerolog.Hook {
+	return &labelsHook{labels: labels, resourceLabels: l.resourceLabels, serviceContext: l.config.ResourceLabels}
+}
+
+// injectedRootLogger returns rootLogger for the injectors, with the hooks added by RootLogger
+// if it doesn't have them, e.g. zerolog.New(os.Stdout), so that WithLabels and StartOperation work with any root logger.
+func (l *Logger) injectedRootLogger(rootLogger *zerolog.Logger) *zerolog.Logger {
+	if hasRootHooks(rootLogger) {
+		return rootLogger
+	}
+	logger := rootLogger.Hook(l.labelsHook(nil), &operationHook{})
+	return &logger
+}
+
+// rootHooksKey is the context key for the probe event of hasRootHooks.
+type rootHooksKey struct{}
+
+// hasRootHooks reports whether logger has the hooks added by RootLogger, by writing a probe event to io.Discard.
+// labelsHook marks and discards the probe event, so that the hooks after it ignore the event.
+func hasRootHooks(logger *zerolog.Logger) bool {
+	found := false
+	probe := logger.Output(io.Discard).Sample(nil)
+	probe.Log().Ctx(context.WithValue(context.Background(), rootHooksKey{}, &found)).Send()
+	return found
+}
+
+// requestLogger returns the logger for the request, which has the timestamp hook and the trace fields.
+func (l *Logger) requestLogger(ctx context.Context, rootLogger *zerolog.Logger, trace TraceContext) zerolog.Logger {
+	logger := rootLogger.With().Logger().Hook(l.timestampHook())
+	l.warnProjectID(&logger)
+
+	if l.config.TraceFromContext != nil {
+		if spanLogger, ok := l.spanLogger(ctx, logger); ok {
+			return spanLogger
+		}
+	}
+
+	if trace.TraceID != "" {
+		logger = l.traceFields(logger.With(), trace.TraceID, trace.SpanID, trace.Sampled).Logger()
+	}
+	return logger
+}
+
+// injectLogger returns a copy of ctx with the logger and the trace for the request, and the logger
+// without the sourceLocation hook for the log entries written by this package.
+// header returns the value of the given request header, or empty string if not present.
+func (l *Logger) injectLogger(ctx context.Context, rootLogger *zerolog.Logger, header func(string) string) (context.Context, zerolog.Logger) {
+	trace := l.traceFromRequest(ctx, header)
+	logger := l.requestLogger(ctx, rootLogger, trace)
+	if trace.TraceID != "" {
+		ctx = context.WithValue(ctx, traceKey{}, trace)
+	}
+	if l.debugLog(trace, header) {
+		// The level is also stored in the event context, so that it takes precedence over the runtime level.
+		ctx = context.WithValue(ctx, debugLevelKey{}, l.config.DebugLogLevel)
+		logger = logger.With().Ctx(ctx).Logger()
+		if l.config.DebugLogLevel < logger.GetLevel() {
+			logger = logger.Level(l.config.DebugLogLevel)
 		}
-		projectID = id
-	} else {
-		projectID = fetchProjectIDFromEnv()
 	}
+	if l.config.RequestID {
+		id := requestID(header(l.config.RequestIDHeader))
+		ctx = context.WithValue(ctx, requestIDKey{}, id)
+		logger = logger.With().Str("requestId", id).Logger()
+	}
+	return l.callSiteHooks(logger).WithContext(ctx), logger
 }
 
+// responseHeader returns the headers to be returned to the client, which are read from ctx
+// returned by injectLogger.
+func (l *Logger) responseHeader(ctx context.Context) map[string]string {
+	header := map[string]string{}
+	if trace, ok := TraceFromContext(ctx); ok && l.config.TraceResponseHeader != "" {
+		header[l.config.TraceResponseHeader] = trace.TraceID
+	}
+	if id, ok := RequestIDFromContext(ctx); ok {
+		header[l.config.RequestIDHeader] = id
+	}
+	return header
+}
+
+// traceFromRequest returns the trace of the request from the span in ctx if configured,
+// or from the trace headers.
+func (l *Logger) traceFromRequest(ctx context.Context, header func(string) string) TraceContext {
+	trace, ok := TraceContext{}, false
+	if l.config.TraceFromContext != nil {
+		trace, ok = l.config.TraceFromContext(ctx)
+	}
+	if !ok {
+		trace.TraceID, trace.SpanID, trace.Sampled = l.traceContext(header)
+	}
+	if trace.TraceID == "" && l.config.GenerateTrace {
+		trace.TraceID, trace.SpanID = newTraceID(), fmt.Sprintf("%016x", newSpanID())
+	}
+	if trace.TraceID != "" {
+		trace.ProjectID = l.projectID()
+		trace.Name = l.traceName(trace.TraceID)
+	}
+	return trace
+}
+
+// timestampHook returns the hook to add the timestamp field.
+func (l *Logger) timestampHook() zerolog.Hook {
+	return &timestampHook{fieldName: l.config.TimeFieldName, format: l.config.TimeFieldFormat}
+}
+
+// callSiteHooks returns a child logger of logger with the hooks which depend on the log site,
+// i.e. sourceLocation and Error Reporting.
+func (l *Logger) callSiteHooks(logger zerolog.Logger) zerolog.Logger {
+	logger = logger.Hook(sourceLocationHook)
+	if l.config.ErrorReporting {
+		// Stack makes zerolog.Event.Err call zerolog.ErrorStackMarshaler, e.g. ErrorStackMarshaler.
+		// serviceContext is added by RootLogger if ResourceLabels is set.
+		logger = logger.With().Stack().Logger().Hook(&errorHook{serviceContext: !l.config.ResourceLabels})
+	}
+	return logger
+}
+
+// withEventContext returns a copy of ctx with the logger whose log events have ctx as their context,
+// so that the hooks can read the values in ctx.
+func withEventContext(ctx context.Context) context.Context {
+	return zerolog.Ctx(ctx).With().Ctx(ctx).Logger().WithContext(ctx)
+}
+
+// std returns the Logger used by the package-level functions.
+// It is created on first use with SetGlobals, so that any zerolog.Logger can be used as the root logger.
+func std() *Logger {
+	defaultLoggerOnce.Do(func() {
+		defaultLogger = New(Config{SetGlobals: true})
+	})
+	return defaultLogger
+}
+
+// stdClient returns the Logger used by the package-level functions for outgoing requests.
+// Unlike std, it doesn't modify zerolog package-level variables, as no root logger is given to them.
+func stdClient() *Logger {
+	defaultClientLoggerOnce.Do(func() {
+		defaultClientLogger = New(Config{})
+	})
+	return defaultClientLogger
+}
+
+// Severity returns the Cloud Logging LogSeverity for the level.
+func Severity(l zerolog.Level) string {
+	// mapping to Cloud Logging LogSeverity
+	// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogSeverity
+	switch l {
+	case zerolog.TraceLevel:
+		return "DEFAULT"
+	case zerolog.DebugLevel:
+		return "DEBUG"
+	case zerolog.InfoLevel:
+		return "INFO"
+	case zerolog.WarnLevel:
+		return "WARNING"
+	case zerolog.ErrorLevel:
+		return "ERROR"
+	case zerolog.FatalLevel:
+		return "CRITICAL"
+	case zerolog.PanicLevel:
+		return "ALERT"
+	case zerolog.NoLevel:
+		return "DEFAULT"
+	default:
+		return "DEFAULT"
+	}
+}
+
+// levelWriter implements zerolog.LevelWriter interface.
+// It replaces the level field written by zerolog with the configured one,
+// so that the output doesn't depend on zerolog.LevelFieldName and zerolog.LevelFieldMarshalFunc.
+type levelWriter struct {
+	w         io.Writer
+	fieldName string
+	marshal   func(zerolog.Level) string
+}
+
+// Write writes p to the underlying writer as it is.
+func (w *levelWriter) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+// WriteLevel writes p to the underlying writer with the configured level field.
+func (w *levelWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level == zerolog.NoLevel || len(p) == 0 || p[0] != '{' {
+		return w.w.Write(p)
+	}
+
+	// zerolog always writes the level field first.
+	rest := p[1:]
+	if zerolog.LevelFieldName != "" {
+		field := strconv.Quote(zerolog.LevelFieldName) + ":" + strconv.Quote(zerolog.LevelFieldMarshalFunc(level))
+		if bytes.HasPrefix(rest, []byte(field)) {
+			rest = bytes.TrimPrefix(rest[len(field):], []byte(","))
+		}
+	}
+
+	buf := make([]byte, 0, len(p)+32)
+	buf = append(buf, '{')
+	buf = strconv.AppendQuote(buf, w.fieldName)
+	buf = append(buf, ':')
+	buf = strconv.AppendQuote(buf, w.marshal(level))
+	if len(rest) > 0 && rest[0] != '}' {
+		buf = append(buf, ',')
+	}
+	buf = append(buf, rest...)
+
+	if _, err := w.w.Write(buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// timestampHook implements zerolog.Hook interface.
+type timestampHook struct {
+	fieldName string
+	format    string
+}
+
+// Run adds the current time for the log to zerolog.Event.
+func (h *timestampHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level == zerolog.Disabled {
+		// Discarded by levelHook.
+		return
+	}
+	e.Str(h.fieldName, time.Now().Format(h.format))
+}
+
+// callerPCKey is the context key for the program counter of the log site,
+// which is set by the bridge from other logging APIs to the context of zerolog.Event.
+type callerPCKey struct{}
+
 // callerHook implements zerolog.Hook interface.
 type callerHook struct{}
 
 // Run adds sourceLocation for the log to zerolog.Event.
 func (h *callerHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level == zerolog.Disabled {
+		// Discarded by levelHook.
+		return
+	}
 	var file, line, function string
-	if pc, filePath, lineNum, ok := runtime.Caller(CallerSkipFrameCount); ok {
+	if pc, ok := e.GetCtx().Value(callerPCKey{}).(uintptr); ok {
+		// The caller is given by the bridge from other logging APIs, e.g. slog.
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		function = frame.Function
+		line = fmt.Sprintf("%d", frame.Line)
+		parts := strings.Split(frame.File, "/")
+		file = parts[len(parts)-1]
+	} else if pc, filePath, lineNum, ok := runtime.Caller(CallerSkipFrameCount); ok {
 		if f := runtime.FuncForPC(pc); f != nil {
 			function = f.Name()
 		}
@@ -85,48 +510,116 @@ func (h *callerHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
 		zerolog.Dict().Str("file", file).Str("line", line).Str("function", function))
 }
 
-func fetchProjectIDFromMetadata() (string, error) {
-	req, err := http.NewRequest("GET",
-		"http://metadata.google.internal/computeMetadata/v1/project/project-id", nil)
-	if err != nil {
-		return "", err
-	}
+// errorHook implements zerolog.Hook interface.
+type errorHook struct {
+	serviceContext bool
+}
 
-	req.Header.Add("Metadata-Flavor", "Google")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
+// Run adds the fields for Error Reporting to zerolog.Event at ErrorLevel or higher.
+func (h *errorHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level < zerolog.ErrorLevel || level == zerolog.NoLevel || level == zerolog.Disabled {
+		return
 	}
-	defer resp.Body.Close()
 
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+	logged, _ := e.GetCtx().Value(errorKey{}).(loggedError)
+	pcs := logged.pcs
+	if pcs == nil {
+		pcs = make([]uintptr, 64)
+		// Skip runtime.Callers and the frames of zerolog as callerHook does.
+		pcs = pcs[:runtime.Callers(CallerSkipFrameCount+1, pcs)]
+		if pc, ok := e.GetCtx().Value(callerPCKey{}).(uintptr); ok {
+			// Skip the frames of the bridge up to the given caller.
+			for i := range pcs {
+				if pcs[i] == pc {
+					pcs = pcs[i:]
+					break
+				}
+			}
+		}
+	}
+	e.Str("@type", reportedErrorEventType).Str("stack_trace", formatStack(reportedMessage(msg, logged.err), pcs))
+	if !h.serviceContext {
+		return
+	}
+	if sc := serviceContext(); sc != nil {
+		e.Dict("serviceContext", sc)
 	}
-	return string(b), nil
-}
-
-func fetchProjectIDFromEnv() string {
-	return os.Getenv("GOOGLE_CLOUD_PROJECT")
 }
 
-func traceContextFromHeader(header string) (string, string) {
+func traceContextFromHeader(header string) (string, string, bool) {
 	matched := traceHeaderRegExp.FindStringSubmatch(header)
-	if len(matched) < 3 {
-		return "", ""
+	if len(matched) < 4 {
+		return "", "", false
 	}
 
-	traceID, spanID := matched[1], matched[2]
+	traceID, spanID, sampled := matched[1], matched[2], matched[3] == "1"
 	if spanID == "" {
-		return traceID, ""
+		return traceID, "", sampled
 	}
 	spanIDInt, err := strconv.ParseUint(spanID, 10, 64)
 	if err != nil {
 		// invalid
-		return "", ""
+		return "", "", false
 	}
 	// spanId for cloud logging must be 16-character hexadecimal number.
 	// See: https://cloud.google.com/trace/docs/trace-log-integration#associating
 	spanIDHex := fmt.Sprintf("%016x", spanIDInt)
-	return traceID, spanIDHex
+	return traceID, spanIDHex, sampled
+}
+
+func traceContextFromTraceparent(header string) (string, string, bool) {
+	matched := traceparentHeaderRegExp.FindStringSubmatch(header)
+	if len(matched) < 6 {
+		return "", "", false
+	}
+
+	version, traceID, spanID, flags := matched[1], matched[2], matched[3], matched[4]
+	// Version ff is invalid, and version 00 must not have any additional fields.
+	if version == "ff" || (version == "00" && matched[5] != "") {
+		return "", "", false
+	}
+	// All zero trace-id and parent-id are invalid.
+	if strings.Trim(traceID, "0") == "" || strings.Trim(spanID, "0") == "" {
+		return "", "", false
+	}
+	flagsInt, err := strconv.ParseUint(flags, 16, 8)
+	if err != nil {
+		// invalid
+		return "", "", false
+	}
+	return traceID, spanID, flagsInt&0x01 == 0x01
+}
+
+// traceContext returns the trace ID, span ID and sampled flag from the first valid trace header.
+// get returns the value of the given header, or empty string if not present.
+func (l *Logger) traceContext(get func(header string) string) (string, string, bool) {
+	for _, header := range l.config.TraceHeaders {
+		var traceID, spanID string
+		var sampled bool
+		switch strings.ToLower(header) {
+		case strings.ToLower(CloudTraceContextHeader):
+			traceID, spanID, sampled = traceContextFromHeader(get(header))
+		case strings.ToLower(TraceparentHeader):
+			traceID, spanID, sampled = traceContextFromTraceparent(get(header))
+		}
+		if traceID != "" {
+			return traceID, spanID, sampled
+		}
+	}
+	return "", "", false
+}
+
+// traceFields adds the trace, spanId and trace_sampled fields to c.
+// See: https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
+func (l *Logger) traceFields(c zerolog.Context, traceID, spanID string, sampled bool) zerolog.Context {
+	c = c.Str("logging.googleapis.com/trace", l.traceName(traceID))
+	if spanID != "" {
+		c = c.Str("logging.googleapis.com/spanId", spanID)
+	}
+	return c.Bool("logging.googleapis.com/trace_sampled", sampled)
+}
+
+// traceName returns the resource name of the trace.
+func (l *Logger) traceName(traceID string) string {
+	return fmt.Sprintf("projects/%s/traces/%s", l.projectID(), traceID)
 }
@@ -1,7 +1,14 @@
 package crzerolog
 
 import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
 )
 
 func TestTraceContextFromHeader(t *testing.T) {
@@ -9,18 +16,176 @@ func TestTraceContextFromHeader(t *testing.T) {
 		header      string
 		wantTraceID string
 		wantSpanID  string
+		wantSampled bool
+	}{
+		{"0123456789abcdef0123456789abcdef/123;o=1", "0123456789abcdef0123456789abcdef", "000000000000007b", true},
+		{"0123456789abcdef0123456789abcdef/123;o=0", "0123456789abcdef0123456789abcdef", "000000000000007b", false},
+		{"0123456789abcdef0123456789abcdef/123", "0123456789abcdef0123456789abcdef", "000000000000007b", false},
+		{"0123456789abcdef0123456789abcdef;o=1", "0123456789abcdef0123456789abcdef", "", true},
+		{"0123456789abcdef0123456789abcdef", "0123456789abcdef0123456789abcdef", "", false},
+		{"0123456789abcdef0123456789abcdef/invalid", "", "", false},
+		{"invalid", "", "", false},
+		{"", "", "", false},
+	} {
+		traceID, spanID, sampled := traceContextFromHeader(tt.header)
+		if traceID != tt.wantTraceID || spanID != tt.wantSpanID || sampled != tt.wantSampled {
+			t.Errorf("traceContextFromHeader(%q) = (%q, %q, %v), want = (%q, %q, %v)", tt.header, traceID, spanID, sampled, tt.wantTraceID, tt.wantSpanID, tt.wantSampled)
+		}
+	}
+}
+
+func TestRootLogger(t *testing.T) {
+	for _, tt := range []struct {
+		desc   string
+		config Config
+		log    func(l *zerolog.Logger)
+		want   string
+	}{
+		{
+			desc:   "Default",
+			config: Config{ProjectID: "myproject"},
+			log:    func(l *zerolog.Logger) { l.Warn().Msg("hello") },
+			want:   `{"severity":"WARNING","message":"hello"}` + "\n",
+		},
+		{
+			desc: "Custom level field",
+			config: Config{
+				ProjectID:             "myproject",
+				LevelFieldName:        "lvl",
+				LevelFieldMarshalFunc: func(l zerolog.Level) string { return strings.ToUpper(l.String()) },
+			},
+			log:  func(l *zerolog.Logger) { l.Error().Str("foo", "bar").Msg("hello") },
+			want: `{"lvl":"ERROR","foo":"bar","message":"hello"}` + "\n",
+		},
+		{
+			desc:   "No level",
+			config: Config{ProjectID: "myproject"},
+			log:    func(l *zerolog.Logger) { l.Log().Msg("hello") },
+			want:   `{"message":"hello"}` + "\n",
+		},
+	} {
+		buf := &bytes.Buffer{}
+		rootLogger := New(tt.config).RootLogger(buf)
+		tt.log(&rootLogger)
+
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: got = %q, want = %q", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestNewDoesNotModifyGlobals(t *testing.T) {
+	levelFieldName, timeFieldFormat := zerolog.LevelFieldName, zerolog.TimeFieldFormat
+	New(Config{ProjectID: "myproject", LevelFieldName: "lvl", TimeFieldFormat: time.Kitchen})
+
+	if zerolog.LevelFieldName != levelFieldName || zerolog.TimeFieldFormat != timeFieldFormat {
+		t.Errorf("New modified zerolog package-level variables")
+	}
+}
+
+func TestTraceContextFromTraceparent(t *testing.T) {
+	for _, tt := range []struct {
+		header      string
+		wantTraceID string
+		wantSpanID  string
+		wantSampled bool
 	}{
-		{"0123456789abcdef0123456789abcdef/123;o=1", "0123456789abcdef0123456789abcdef", "000000000000007b"},
-		{"0123456789abcdef0123456789abcdef/123;o=0", "0123456789abcdef0123456789abcdef", "000000000000007b"},
-		{"0123456789abcdef0123456789abcdef/123", "0123456789abcdef0123456789abcdef", "000000000000007b"},
-		{"0123456789abcdef0123456789abcdef", "0123456789abcdef0123456789abcdef", ""},
-		{"0123456789abcdef0123456789abcdef/invalid", "", ""},
-		{"invalid", "", ""},
-		{"", "", ""},
+		{"00-0123456789abcdef0123456789abcdef-0123456789abcdef-01", "0123456789abcdef0123456789abcdef", "0123456789abcdef", true},
+		{"00-0123456789abcdef0123456789abcdef-0123456789abcdef-00", "0123456789abcdef0123456789abcdef", "0123456789abcdef", false},
+		{"01-0123456789abcdef0123456789abcdef-0123456789abcdef-03-future", "0123456789abcdef0123456789abcdef", "0123456789abcdef", true},
+		{"00-0123456789abcdef0123456789abcdef-0123456789abcdef-01-invalid", "", "", false},
+		{"ff-0123456789abcdef0123456789abcdef-0123456789abcdef-01", "", "", false},
+		{"00-00000000000000000000000000000000-0123456789abcdef-01", "", "", false},
+		{"00-0123456789abcdef0123456789abcdef-0000000000000000-01", "", "", false},
+		{"00-0123456789ABCDEF0123456789ABCDEF-0123456789abcdef-01", "", "", false},
+		{"invalid", "", "", false},
+		{"", "", "", false},
 	} {
-		traceID, spanID := traceContextFromHeader(tt.header)
-		if traceID != tt.wantTraceID || spanID != tt.wantSpanID {
-			t.Errorf("traceContextFromHeader(%q) = (%q, %q), want = (%q, %q)", tt.header, traceID, spanID, tt.wantTraceID, tt.wantSpanID)
+		traceID, spanID, sampled := traceContextFromTraceparent(tt.header)
+		if traceID != tt.wantTraceID || spanID != tt.wantSpanID || sampled != tt.wantSampled {
+			t.Errorf("traceContextFromTraceparent(%q) = (%q, %q, %v), want = (%q, %q, %v)", tt.header, traceID, spanID, sampled, tt.wantTraceID, tt.wantSpanID, tt.wantSampled)
 		}
 	}
 }
+
+func TestTraceContextPrecedence(t *testing.T) {
+	headers := map[string]string{
+		CloudTraceContextHeader: "0123456789abcdef0123456789abcdef/123;o=1",
+		TraceparentHeader:       "00-fedcba9876543210fedcba9876543210-fedcba9876543210-00",
+	}
+	get := func(header string) string { return headers[header] }
+
+	for _, tt := range []struct {
+		desc        string
+		headers     []string
+		wantTraceID string
+	}{
+		{"Default", nil, "0123456789abcdef0123456789abcdef"},
+		{"traceparent first", []string{TraceparentHeader, CloudTraceContextHeader}, "fedcba9876543210fedcba9876543210"},
+		{"traceparent only", []string{TraceparentHeader}, "fedcba9876543210fedcba9876543210"},
+		{"Fallback", []string{"X-Unknown", TraceparentHeader}, "fedcba9876543210fedcba9876543210"},
+	} {
+		l := New(Config{ProjectID: "myproject", TraceHeaders: tt.headers})
+		if traceID, _, _ := l.traceContext(get); traceID != tt.wantTraceID {
+			t.Errorf("%s: traceID = %q, want = %q", tt.desc, traceID, tt.wantTraceID)
+		}
+	}
+}
+
+func TestRootLoggerResourceLabels(t *testing.T) {
+	t.Setenv("K_SERVICE", "myservice")
+	newTestMetadataServer(t, metadataValues(map[string]string{}))
+	t.Setenv("K_REVISION", "myservice-00001-abc")
+
+	buf := &bytes.Buffer{}
+	rootLogger := New(Config{ProjectID: "myproject", Platform: PlatformCloudRun, ResourceLabels: true}).RootLogger(buf)
+	rootLogger.Info().Msg("hello")
+
+	want := `{"severity":"INFO","logging.googleapis.com/labels":{"revision_name":"myservice-00001-abc","service_name":"myservice"},` +
+		`"serviceContext":{"service":"myservice","version":"myservice-00001-abc"},"message":"hello"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got = %q, want = %q", got, want)
+	}
+}
+
+func TestNewResourceLabelsInBackground(t *testing.T) {
+	t.Setenv("K_SERVICE", "myservice")
+	release := make(chan struct{})
+	values := metadataValues(map[string]string{"instance/id": "0123456789"})
+	newTestMetadataServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		values(w, r)
+	})
+
+	// New must not wait for the metadata server.
+	logger := New(Config{ProjectID: "myproject", Platform: PlatformCloudRun, ResourceLabels: true})
+	close(release)
+
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+	rootLogger.Info().Msg("hello")
+
+	want := `{"severity":"INFO","logging.googleapis.com/labels":{"instanceId":"0123456789","service_name":"myservice"},` +
+		`"serviceContext":{"service":"myservice"},"message":"hello"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got = %q, want = %q", got, want)
+	}
+}
+
+// useDefaultLogger makes the package-level functions use a new default Logger resolving projectID from the env var,
+// and restores the zerolog package-level variables modified by it after the test.
+func useDefaultLogger(t *testing.T, projectID string) {
+	t.Helper()
+	t.Setenv("GOOGLE_CLOUD_PROJECT", projectID)
+	timeFieldFormat, timestampFieldName := zerolog.TimeFieldFormat, zerolog.TimestampFieldName
+	levelFieldName, levelFieldMarshalFunc := zerolog.LevelFieldName, zerolog.LevelFieldMarshalFunc
+	defaultLogger, defaultLoggerOnce = nil, sync.Once{}
+	defaultClientLogger, defaultClientLoggerOnce = nil, sync.Once{}
+
+	t.Cleanup(func() {
+		zerolog.TimeFieldFormat, zerolog.TimestampFieldName = timeFieldFormat, timestampFieldName
+		zerolog.LevelFieldName, zerolog.LevelFieldMarshalFunc = levelFieldName, levelFieldMarshalFunc
+		defaultLogger, defaultLoggerOnce = nil, sync.Once{}
+		defaultClientLogger, defaultClientLoggerOnce = nil, sync.Once{}
+	})
+}
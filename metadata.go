@@ -0,0 +1,153 @@
+package crzerolog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// metadataHostEnv is the env var to override the metadata server host, e.g. for tests.
+	// It's the same env var as used by cloud.google.com/go/compute/metadata.
+	metadataHostEnv     = "GCE_METADATA_HOST"
+	defaultMetadataHost = "metadata.google.internal"
+)
+
+// defaultMetadataClient is the metadata client shared in the package,
+// so that the fetched values are cached in the process.
+var defaultMetadataClient = &metadataClient{
+	client:   &http.Client{},
+	timeout:  2 * time.Second,
+	retries:  3,
+	backoff:  100 * time.Millisecond,
+	deadline: 10 * time.Second,
+}
+
+// metadataClient is a client for the metadata server, which caches fetched values.
+// See: https://cloud.google.com/compute/docs/metadata/querying-metadata
+type metadataClient struct {
+	client *http.Client
+	// timeout is the timeout of each attempt.
+	timeout time.Duration
+	// retries is the max number of retries.
+	retries int
+	// backoff is the initial backoff before retry, which doubles on every retry.
+	backoff time.Duration
+	// deadline is the overall deadline of fetching a value, including retries.
+	deadline time.Duration
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+// metadataStatusError is returned when the metadata server responds with non-200 status.
+type metadataStatusError struct {
+	path       string
+	statusCode int
+}
+
+func (e *metadataStatusError) Error() string {
+	return fmt.Sprintf("metadata server responded %d for %q", e.statusCode, e.path)
+}
+
+// get returns the metadata value for path, e.g. "project/project-id".
+func (c *metadataClient) get(ctx context.Context, path string) (string, error) {
+	c.mu.Lock()
+	v, ok := c.cache[path]
+	c.mu.Unlock()
+	if ok {
+		return v, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.deadline)
+	defer cancel()
+
+	backoff := c.backoff
+	for i := 0; ; i++ {
+		v, err := c.fetch(ctx, path)
+		if err == nil {
+			c.mu.Lock()
+			if c.cache == nil {
+				c.cache = map[string]string{}
+			}
+			c.cache[path] = v
+			c.mu.Unlock()
+			return v, nil
+		}
+		if se, ok := err.(*metadataStatusError); ok && se.statusCode < 500 {
+			// Client errors such as 404 are not retryable.
+			return "", err
+		}
+		if i >= c.retries {
+			return "", err
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// fetch makes a single request to the metadata server.
+func (c *metadataClient) fetch(ctx context.Context, path string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	host := os.Getenv(metadataHostEnv)
+	if host == "" {
+		host = defaultMetadataHost
+	}
+	req, err := http.NewRequest("GET", "http://"+host+"/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Add("Metadata-Flavor", "Google")
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", &metadataStatusError{path: path, statusCode: resp.StatusCode}
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// projectID returns the project ID.
+func (c *metadataClient) projectID(ctx context.Context) (string, error) {
+	return c.get(ctx, "project/project-id")
+}
+
+// region returns the region, e.g. "us-central1".
+func (c *metadataClient) region(ctx context.Context) (string, error) {
+	// The value is in the form of "projects/123456789/regions/us-central1".
+	v, err := c.get(ctx, "instance/region")
+	if err != nil {
+		return "", err
+	}
+	return v[strings.LastIndex(v, "/")+1:], nil
+}
+
+// instanceID returns the instance ID.
+func (c *metadataClient) instanceID(ctx context.Context) (string, error) {
+	return c.get(ctx, "instance/id")
+}
+
+// serviceAccount returns the email of the default service account.
+func (c *metadataClient) serviceAccount(ctx context.Context) (string, error) {
+	return c.get(ctx, "instance/service-accounts/default/email")
+}
@@ -0,0 +1,126 @@
+package crzerolog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestMetadataServer starts a metadata server stand-in which responds values by path,
+// and replaces defaultMetadataClient during the test.
+func newTestMetadataServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	t.Setenv(metadataHostEnv, strings.TrimPrefix(server.URL, "http://"))
+
+	orig := defaultMetadataClient
+	defaultMetadataClient = newTestMetadataClient()
+	t.Cleanup(func() { defaultMetadataClient = orig })
+}
+
+func newTestMetadataClient() *metadataClient {
+	return &metadataClient{
+		client:   &http.Client{},
+		timeout:  time.Second,
+		retries:  2,
+		backoff:  time.Millisecond,
+		deadline: 5 * time.Second,
+	}
+}
+
+func metadataValues(values map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "Missing Metadata-Flavor header", http.StatusForbidden)
+			return
+		}
+		v, ok := values[strings.TrimPrefix(r.URL.Path, "/computeMetadata/v1/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(v))
+	}
+}
+
+func TestMetadataClient(t *testing.T) {
+	newTestMetadataServer(t, metadataValues(map[string]string{
+		"project/project-id": "myproject",
+		"instance/region":    "projects/123456789/regions/us-central1",
+		"instance/id":        "0123456789",
+		"instance/service-accounts/default/email": "sa@myproject.iam.gserviceaccount.com",
+	}))
+	c := defaultMetadataClient
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		desc string
+		get  func(context.Context) (string, error)
+		want string
+	}{
+		{"projectID", c.projectID, "myproject"},
+		{"region", c.region, "us-central1"},
+		{"instanceID", c.instanceID, "0123456789"},
+		{"serviceAccount", c.serviceAccount, "sa@myproject.iam.gserviceaccount.com"},
+	} {
+		got, err := tt.get(ctx)
+		if err != nil {
+			t.Errorf("%s: Unexpected error: %v", tt.desc, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got = %q, want = %q", tt.desc, got, tt.want)
+		}
+	}
+
+	if _, err := c.get(ctx, "instance/unknown"); err == nil {
+		t.Errorf("Expected error for 404, but got nil")
+	}
+}
+
+func TestMetadataClientRetry(t *testing.T) {
+	var count int32
+	newTestMetadataServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) < 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("myproject"))
+	})
+	c := defaultMetadataClient
+
+	got, err := c.projectID(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "myproject" {
+		t.Errorf("got = %q, want = %q", got, "myproject")
+	}
+
+	// The value is cached.
+	if _, err := c.projectID(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Request count = %d, want = %d", count, 3)
+	}
+}
+
+func TestMetadataClientNoRetryOnClientError(t *testing.T) {
+	var count int32
+	newTestMetadataServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		http.NotFound(w, r)
+	})
+
+	if _, err := defaultMetadataClient.projectID(context.Background()); err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+	if count != 1 {
+		t.Errorf("Request count = %d, want = %d", count, 1)
+	}
+}
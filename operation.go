@@ -0,0 +1,74 @@
+package crzerolog
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// operationKey is the context key for the operation started by StartOperation.
+type operationKey struct{}
+
+// operation is a long-running operation which groups log entries in Cloud Logging.
+type operation struct {
+	id       string
+	producer string
+	started  atomic.Bool
+}
+
+// operationValue is the value stored in the context for operationKey.
+type operationValue struct {
+	op   *operation
+	last bool
+}
+
+// StartOperation returns a copy of ctx with the logger which adds logging.googleapis.com/operation
+// with id and producer to the log entries.
+// The first entry of the operation is marked with first: true.
+// See: https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogEntryOperation
+//
+// The logger in ctx must be the one injected by the injectors, or derived from the logger returned by Logger.RootLogger.
+func StartOperation(ctx context.Context, id, producer string) context.Context {
+	return withOperation(ctx, operationValue{op: &operation{id: id, producer: producer}})
+}
+
+// EndOperation returns a copy of ctx with the logger which marks the log entries
+// as the last entry of the operation started by StartOperation, with last: true.
+// It returns ctx as it is if no operation is started.
+func EndOperation(ctx context.Context) context.Context {
+	v, ok := ctx.Value(operationKey{}).(operationValue)
+	if !ok {
+		return ctx
+	}
+	v.last = true
+	return withOperation(ctx, v)
+}
+
+func withOperation(ctx context.Context, v operationValue) context.Context {
+	return withEventContext(context.WithValue(ctx, operationKey{}, v))
+}
+
+// operationHook implements zerolog.Hook interface.
+type operationHook struct{}
+
+// Run adds logging.googleapis.com/operation to zerolog.Event if the operation is started.
+func (h *operationHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level == zerolog.Disabled {
+		// Discarded by levelHook.
+		return
+	}
+	v, ok := e.GetCtx().Value(operationKey{}).(operationValue)
+	if !ok {
+		return
+	}
+
+	op := zerolog.Dict().Str("id", v.op.id).Str("producer", v.op.producer)
+	if v.op.started.CompareAndSwap(false, true) {
+		op = op.Bool("first", true)
+	}
+	if v.last {
+		op = op.Bool("last", true)
+	}
+	e.Dict("logging.googleapis.com/operation", op)
+}
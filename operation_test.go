@@ -0,0 +1,112 @@
+package crzerolog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+type operationEntry struct {
+	ID       string `json:"id"`
+	Producer string `json:"producer"`
+	First    bool   `json:"first"`
+	Last     bool   `json:"last"`
+}
+
+func TestOperation(t *testing.T) {
+	logger := New(Config{ProjectID: "myproject"})
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := StartOperation(r.Context(), "op-1", "example.com/job")
+		log.Ctx(ctx).Info().Msg("start")
+		log.Ctx(ctx).Debug().Msg("ignored") // Debug log doesn't consume the first entry
+		log.Ctx(ctx).Info().Msg("step")
+		log.Ctx(EndOperation(ctx)).Info().Msg("end")
+		log.Ctx(r.Context()).Info().Msg("outside")
+	})
+	logger.InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var got []*operationEntry
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var entry struct {
+			Operation *operationEntry `json:"logging.googleapis.com/operation"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		got = append(got, entry.Operation)
+	}
+
+	want := []*operationEntry{
+		{ID: "op-1", Producer: "example.com/job", First: true},
+		{ID: "op-1", Producer: "example.com/job"},
+		{ID: "op-1", Producer: "example.com/job", Last: true},
+		nil,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Operation diff: %s", diff)
+	}
+}
+
+func TestOperationBelowRuntimeLevel(t *testing.T) {
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	defer zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	logger := New(Config{ProjectID: "myproject", Level: zerolog.InfoLevel})
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := StartOperation(r.Context(), "op-1", "example.com/job")
+		// The debug event is built and discarded by the runtime level, which doesn't consume the first entry.
+		log.Ctx(ctx).Debug().Msg("ignored")
+		log.Ctx(ctx).Info().Msg("start")
+	})
+	logger.InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var got struct {
+		Operation *operationEntry `json:"logging.googleapis.com/operation"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := &operationEntry{ID: "op-1", Producer: "example.com/job", First: true}
+	if diff := cmp.Diff(want, got.Operation); diff != "" {
+		t.Errorf("Operation diff: %s", diff)
+	}
+}
+
+func TestOperationPackageLevel(t *testing.T) {
+	useDefaultLogger(t, "myproject")
+	buf := &bytes.Buffer{}
+	rootLogger := zerolog.New(buf)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := StartOperation(r.Context(), "op-1", "example.com/job")
+		log.Ctx(ctx).Info().Msg("start")
+	})
+	InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var got struct {
+		Operation *operationEntry `json:"logging.googleapis.com/operation"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := &operationEntry{ID: "op-1", Producer: "example.com/job", First: true}
+	if diff := cmp.Diff(want, got.Operation); diff != "" {
+		t.Errorf("Operation diff: %s", diff)
+	}
+}
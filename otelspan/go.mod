@@ -0,0 +1,23 @@
+module github.com/yfuruyama/crzerolog/otelspan
+
+go 1.21
+
+replace github.com/yfuruyama/crzerolog => ../
+
+require (
+	github.com/rs/zerolog v1.33.0
+	github.com/yfuruyama/crzerolog v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+require (
+	github.com/golang/protobuf v1.3.4 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.19 // indirect
+	go.opentelemetry.io/otel v1.28.0 // indirect
+	golang.org/x/net v0.0.0-20200301022130-244492dfa37a // indirect
+	golang.org/x/sys v0.12.0 // indirect
+	golang.org/x/text v0.3.2 // indirect
+	google.golang.org/genproto v0.0.0-20200306153348-d950eab6f860 // indirect
+	google.golang.org/grpc v1.27.1 // indirect
+)
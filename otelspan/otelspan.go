@@ -0,0 +1,22 @@
+// Package otelspan reads the trace for crzerolog from the OpenTelemetry span.
+// It's a separate module, so that crzerolog doesn't depend on OpenTelemetry.
+//
+//	logger := crzerolog.New(crzerolog.Config{TraceFromContext: otelspan.TraceFromContext})
+package otelspan
+
+import (
+	"context"
+
+	"github.com/yfuruyama/crzerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceFromContext returns the trace of the OpenTelemetry span in ctx, or false if ctx has no valid span.
+// It's intended for crzerolog.Config.TraceFromContext.
+func TraceFromContext(ctx context.Context) (crzerolog.TraceContext, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return crzerolog.TraceContext{}, false
+	}
+	return crzerolog.TraceContext{TraceID: sc.TraceID().String(), SpanID: sc.SpanID().String(), Sampled: sc.IsSampled()}, true
+}
@@ -0,0 +1,54 @@
+package otelspan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/yfuruyama/crzerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceFromContext(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("0123456789abcdef0123456789abcdef")
+	spanID, _ := trace.SpanIDFromHex("000000000000007b")
+	childSpanID, _ := trace.SpanIDFromHex("00000000000001c8")
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	logger := crzerolog.New(crzerolog.Config{ProjectID: "myproject", TraceFromContext: TraceFromContext})
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := trace.ContextWithSpanContext(r.Context(), spanContext.WithSpanID(childSpanID))
+		log.Ctx(ctx).Info().Ctx(ctx).Msg("hello")
+	})
+	req := httptest.NewRequest("GET", "/", nil).WithContext(trace.ContextWithSpanContext(context.Background(), spanContext))
+	logger.InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	var got struct {
+		Trace        string `json:"logging.googleapis.com/trace"`
+		SpanID       string `json:"logging.googleapis.com/spanId"`
+		TraceSampled bool   `json:"logging.googleapis.com/trace_sampled"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.Trace != "projects/myproject/traces/0123456789abcdef0123456789abcdef" || got.SpanID != "00000000000001c8" || !got.TraceSampled {
+		t.Errorf("Trace fields = %+v, want the child span", got)
+	}
+
+	if _, ok := TraceFromContext(context.Background()); ok {
+		t.Errorf("TraceFromContext without span = true, want = false")
+	}
+}
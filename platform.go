@@ -1,6 +1,78 @@
 package crzerolog
 
-import "os"
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// Platform is the platform where the application is running.
+type Platform int
+
+const (
+	// PlatformUnknown is a platform other than Google Cloud, such as local environment.
+	PlatformUnknown Platform = iota
+	// PlatformCloudRun is Cloud Run services.
+	PlatformCloudRun
+	// PlatformCloudRunJob is Cloud Run Jobs.
+	PlatformCloudRunJob
+	// PlatformAppEngine is App Engine standard environment (2nd-generation).
+	PlatformAppEngine
+	// PlatformCloudFunctions is Cloud Functions (1st gen and 2nd gen).
+	PlatformCloudFunctions
+	// PlatformGKE is Google Kubernetes Engine.
+	PlatformGKE
+	// PlatformGCE is Compute Engine.
+	PlatformGCE
+)
+
+// String returns the name of the platform.
+func (p Platform) String() string {
+	switch p {
+	case PlatformCloudRun:
+		return "Cloud Run"
+	case PlatformCloudRunJob:
+		return "Cloud Run Jobs"
+	case PlatformAppEngine:
+		return "App Engine"
+	case PlatformCloudFunctions:
+		return "Cloud Functions"
+	case PlatformGKE:
+		return "GKE"
+	case PlatformGCE:
+		return "Compute Engine"
+	default:
+		return "Unknown"
+	}
+}
+
+// OnGoogleCloud reports whether p is a Google Cloud platform, where the metadata server is available.
+func (p Platform) OnGoogleCloud() bool {
+	return p != PlatformUnknown
+}
+
+// DetectPlatform detects the platform where the application is running.
+func DetectPlatform() Platform {
+	switch {
+	case isCloudFunctions():
+		// Cloud Functions must be checked before Cloud Run since 2nd gen is built on Cloud Run.
+		return PlatformCloudFunctions
+	case isCloudRunJob():
+		return PlatformCloudRunJob
+	case isCloudRun():
+		return PlatformCloudRun
+	case isAppEngineSecond():
+		return PlatformAppEngine
+	case isGKE():
+		return PlatformGKE
+	case isGCE():
+		return PlatformGCE
+	default:
+		return PlatformUnknown
+	}
+}
 
 func isCloudRun() bool {
 	// There is no obvious way to detect whether the app is running on Clodu Run,
@@ -10,7 +82,140 @@ func isCloudRun() bool {
 	return os.Getenv("K_CONFIGURATION") != ""
 }
 
+func isCloudRunJob() bool {
+	// ref. https://cloud.google.com/run/docs/container-contract#jobs-env-vars
+	return os.Getenv("CLOUD_RUN_JOB") != ""
+}
+
+func isCloudFunctions() bool {
+	// ref. https://cloud.google.com/functions/docs/configuring/env-var#runtime_environment_variables_set_automatically
+	return os.Getenv("FUNCTION_TARGET") != ""
+}
+
+func isGKE() bool {
+	// KUBERNETES_SERVICE_HOST is set by Kubernetes, and GKE nodes are Compute Engine instances.
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" && isGCE()
+}
+
+// dmiProductNamePath is the file of the product name in the SMBIOS system information, replaced in tests.
+var dmiProductNamePath = "/sys/class/dmi/id/product_name"
+
+func isGCE() bool {
+	// Compute Engine instances have the product name in the SMBIOS system information.
+	// ref. https://cloud.google.com/compute/docs/instances/detect-compute-engine
+	b, err := os.ReadFile(dmiProductNamePath)
+	if err != nil {
+		return false
+	}
+	name := strings.TrimSpace(string(b))
+	return name == "Google" || name == "Google Compute Engine"
+}
+
 func isAppEngineSecond() bool {
 	// ref. https://cloud.google.com/appengine/docs/standard/go/runtime#environment_variables
 	return os.Getenv("GAE_ENV") == "standard"
 }
+
+// serviceContext returns serviceContext for Error Reporting, or nil if the service name is unknown.
+// See: https://cloud.google.com/error-reporting/reference/rest/v1beta1/ServiceContext
+func serviceContext() *zerolog.Event {
+	service := os.Getenv("K_SERVICE")
+	if service == "" {
+		service = os.Getenv("CLOUD_RUN_JOB")
+	}
+	if service == "" {
+		return nil
+	}
+	sc := zerolog.Dict().Str("service", service)
+	if version := os.Getenv("K_REVISION"); version != "" {
+		sc = sc.Str("version", version)
+	}
+	return sc
+}
+
+// resourceLabels returns the labels of the running resource, resolving them on first call.
+// It returns nil unless ResourceLabels is set.
+func (l *Logger) resourceLabels() map[string]string {
+	if !l.config.ResourceLabels {
+		return nil
+	}
+	l.labelsOnce.Do(func() {
+		l.labels = resourceLabels(l.config.Platform)
+	})
+	return l.labels
+}
+
+// resourceLabels returns the labels of the resource running on platform.
+// The labels are named after the monitored resource labels of each platform,
+// except instanceId of Cloud Run and Cloud Functions, which is not a resource label
+// but copies the label of the request logs written by the platform itself.
+// See: https://cloud.google.com/logging/docs/api/v2/resource-list
+func resourceLabels(platform Platform) map[string]string {
+	var envs map[string]string
+	switch platform {
+	case PlatformCloudRun:
+		envs = map[string]string{
+			"service_name":       "K_SERVICE",
+			"revision_name":      "K_REVISION",
+			"configuration_name": "K_CONFIGURATION",
+		}
+	case PlatformCloudFunctions:
+		envs = map[string]string{
+			"function_name": "K_SERVICE",
+		}
+	case PlatformAppEngine:
+		envs = map[string]string{
+			"module_id":  "GAE_SERVICE",
+			"version_id": "GAE_VERSION",
+		}
+	case PlatformGKE:
+		envs = map[string]string{
+			"pod_name":       "HOSTNAME",
+			"namespace_name": "POD_NAMESPACE",
+		}
+	}
+
+	labels := map[string]string{}
+	for key, env := range envs {
+		if v := os.Getenv(env); v != "" {
+			labels[key] = v
+		}
+	}
+
+	ctx := context.Background()
+	switch platform {
+	case PlatformCloudRun, PlatformCloudFunctions:
+		if id, err := defaultMetadataClient.instanceID(ctx); err == nil {
+			// The same key as the request logs, so that the logs of an instance can be filtered together.
+			labels["instanceId"] = id
+		}
+		if region, err := defaultMetadataClient.region(ctx); err == nil {
+			labels["location"] = region
+		}
+	case PlatformGKE:
+		if name, err := defaultMetadataClient.get(ctx, "instance/attributes/cluster-name"); err == nil {
+			labels["cluster_name"] = name
+		}
+	case PlatformGCE:
+		if id, err := defaultMetadataClient.instanceID(ctx); err == nil {
+			labels["instance_id"] = id
+		}
+	}
+	return labels
+}
+
+// jobLabels returns the labels of the running Cloud Run Jobs task.
+func jobLabels() map[string]string {
+	labels := map[string]string{}
+	for key, env := range map[string]string{
+		"job_name":       "CLOUD_RUN_JOB",
+		"execution_name": "CLOUD_RUN_EXECUTION",
+		"task_index":     "CLOUD_RUN_TASK_INDEX",
+		"task_attempt":   "CLOUD_RUN_TASK_ATTEMPT",
+	} {
+		if v := os.Getenv(env); v != "" {
+			labels[key] = v
+		}
+	}
+	return labels
+}
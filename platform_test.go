@@ -0,0 +1,48 @@
+package crzerolog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectPlatform(t *testing.T) {
+	for _, tt := range []struct {
+		desc        string
+		env         map[string]string
+		productName string
+		want        Platform
+	}{
+		{"Cloud Run", map[string]string{"K_SERVICE": "s", "K_REVISION": "r", "K_CONFIGURATION": "c"}, "", PlatformCloudRun},
+		{"Cloud Run Jobs", map[string]string{"CLOUD_RUN_JOB": "j", "CLOUD_RUN_EXECUTION": "e"}, "", PlatformCloudRunJob},
+		{"Cloud Functions", map[string]string{"K_SERVICE": "s", "K_REVISION": "r", "K_CONFIGURATION": "c", "FUNCTION_TARGET": "F"}, "", PlatformCloudFunctions},
+		{"App Engine", map[string]string{"GAE_ENV": "standard"}, "", PlatformAppEngine},
+		{"GKE", map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"}, "Google Compute Engine", PlatformGKE},
+		{"Kubernetes outside GKE", map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"}, "Other", PlatformUnknown},
+		{"Compute Engine", map[string]string{}, "Google Compute Engine", PlatformGCE},
+		{"Compute Engine with old product name", map[string]string{}, "Google", PlatformGCE},
+		{"Unknown", map[string]string{}, "", PlatformUnknown},
+	} {
+		for _, env := range []string{"K_SERVICE", "K_REVISION", "K_CONFIGURATION", "CLOUD_RUN_JOB", "CLOUD_RUN_EXECUTION", "FUNCTION_TARGET", "GAE_ENV", "KUBERNETES_SERVICE_HOST"} {
+			t.Setenv(env, tt.env[env])
+		}
+		useProductName(t, tt.productName)
+		if got := DetectPlatform(); got != tt.want {
+			t.Errorf("%s: DetectPlatform() = %v, want = %v", tt.desc, got, tt.want)
+		}
+	}
+}
+
+// useProductName makes isGCE read name as the product name, or no product name if name is empty.
+func useProductName(t *testing.T, name string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "product_name")
+	if name != "" {
+		if err := os.WriteFile(path, []byte(name+"\n"), 0o644); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	orig := dmiProductNamePath
+	dmiProductNamePath = path
+	t.Cleanup(func() { dmiProductNamePath = orig })
+}
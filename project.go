@@ -0,0 +1,63 @@
+package crzerolog
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// projectIDEnvs is the list of env vars to read the project ID from, in order of precedence.
+var projectIDEnvs = []string{"GOOGLE_CLOUD_PROJECT", "GCLOUD_PROJECT", "CLOUDSDK_CORE_PROJECT"}
+
+// projectID returns the project ID, resolving it on first call if not configured.
+func (l *Logger) projectID() string {
+	if l.config.ProjectID != "" {
+		return l.config.ProjectID
+	}
+	l.projectIDOnce.Do(l.resolveProjectID)
+	return l.resolvedProjectID
+}
+
+// resolveProjectID resolves the project ID from the metadata server, the env vars
+// or FallbackProjectID in this order.
+func (l *Logger) resolveProjectID() {
+	if l.config.Platform.OnGoogleCloud() {
+		id, err := defaultMetadataClient.projectID(context.Background())
+		if err == nil {
+			l.resolvedProjectID = id
+			return
+		}
+		l.projectIDErr = err
+	}
+
+	for _, env := range projectIDEnvs {
+		if id := os.Getenv(env); id != "" {
+			l.resolvedProjectID = id
+			return
+		}
+	}
+	if l.config.FallbackProjectID != "" {
+		l.resolvedProjectID = l.config.FallbackProjectID
+		return
+	}
+	if l.projectIDErr == nil {
+		l.projectIDErr = errors.New("project ID is not found in the environment")
+	}
+}
+
+// warnProjectID writes a warning entry to logger only once, if the project ID couldn't be resolved
+// from the expected source. It doesn't warn outside Google Cloud, where the project ID is not expected.
+func (l *Logger) warnProjectID(logger *zerolog.Logger) {
+	if l.config.Platform == PlatformUnknown {
+		return
+	}
+	if l.projectID() != "" && l.projectIDErr == nil {
+		return
+	}
+	l.warnProjectIDOnce.Do(func() {
+		logger.Warn().Err(l.projectIDErr).Str("projectId", l.resolvedProjectID).
+			Msg("Failed to resolve project ID for the trace field")
+	})
+}
@@ -0,0 +1,110 @@
+package crzerolog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestProjectIDResolution(t *testing.T) {
+	for _, tt := range []struct {
+		desc          string
+		metadata      http.HandlerFunc
+		env           map[string]string
+		fallback      string
+		wantTrace     string
+		wantWarnCount int
+	}{
+		{
+			desc:      "Metadata server",
+			metadata:  metadataValues(map[string]string{"project/project-id": "metaproject"}),
+			env:       map[string]string{"GOOGLE_CLOUD_PROJECT": "envproject"},
+			wantTrace: "projects/metaproject/traces/0123456789abcdef0123456789abcdef",
+		},
+		{
+			desc: "Env var fallback",
+			metadata: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			},
+			env:           map[string]string{"GCLOUD_PROJECT": "envproject"},
+			wantTrace:     "projects/envproject/traces/0123456789abcdef0123456789abcdef",
+			wantWarnCount: 1,
+		},
+		{
+			desc:          "User-provided fallback",
+			metadata:      metadataValues(map[string]string{}),
+			fallback:      "fallbackproject",
+			wantTrace:     "projects/fallbackproject/traces/0123456789abcdef0123456789abcdef",
+			wantWarnCount: 1,
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			newTestMetadataServer(t, tt.metadata)
+			for _, env := range projectIDEnvs {
+				t.Setenv(env, tt.env[env])
+			}
+
+			logger := New(Config{Platform: PlatformCloudRun, FallbackProjectID: tt.fallback})
+			buf := &bytes.Buffer{}
+			rootLogger := logger.RootLogger(buf)
+			zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+			handler := logger.InjectLogger(&rootLogger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				log.Ctx(r.Context()).Info().Msg("hello")
+			}))
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest("GET", "/", nil)
+				req.Header.Add("X-Cloud-Trace-Context", "0123456789abcdef0123456789abcdef/123;o=1")
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+
+			var warnCount int
+			scanner := bufio.NewScanner(buf)
+			for scanner.Scan() {
+				var got logEntry
+				if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if got.Severity == "WARNING" {
+					if got.Time == "" {
+						t.Errorf("Warning has no time: %s", scanner.Text())
+					}
+					warnCount++
+					continue
+				}
+				if got.Trace != tt.wantTrace {
+					t.Errorf("Trace = %q, want = %q", got.Trace, tt.wantTrace)
+				}
+			}
+			if warnCount != tt.wantWarnCount {
+				t.Errorf("Warning count = %d, want = %d", warnCount, tt.wantWarnCount)
+			}
+		})
+	}
+}
+
+func TestProjectIDWarningOutsideGoogleCloud(t *testing.T) {
+	for _, env := range projectIDEnvs {
+		t.Setenv(env, "")
+	}
+	logger := New(Config{})
+	if logger.config.Platform != PlatformUnknown {
+		t.Skipf("Running on %v", logger.config.Platform)
+	}
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	handler := logger.InjectLogger(&rootLogger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if buf.Len() != 0 {
+		t.Errorf("Unexpected warning: %s", buf.String())
+	}
+}
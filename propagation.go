@@ -0,0 +1,55 @@
+package crzerolog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// outgoingTraceHeaders returns the values of X-Cloud-Trace-Context and traceparent headers
+// to continue the trace in ctx with a new span ID.
+// The traceparent value is empty if the trace ID is not valid for it.
+func outgoingTraceHeaders(ctx context.Context) (cloudTraceContext string, traceparent string, ok bool) {
+	trace, ok := TraceFromContext(ctx)
+	if !ok {
+		return "", "", false
+	}
+
+	spanID := newSpanID()
+	var sampled int
+	if trace.Sampled {
+		sampled = 1
+	}
+	// X-Cloud-Trace-Context has the span ID in decimal.
+	cloudTraceContext = fmt.Sprintf("%s/%d;o=%d", trace.TraceID, spanID, sampled)
+	// traceparent requires 32-character lowercase hexadecimal trace ID, which is not all zero.
+	if traceID := strings.ToLower(trace.TraceID); len(traceID) == 32 && strings.Trim(traceID, "0") != "" {
+		traceparent = fmt.Sprintf("00-%s-%016x-%02x", traceID, spanID, sampled)
+	}
+	return cloudTraceContext, traceparent, true
+}
+
+// newTraceID returns a random 32-character hexadecimal trace ID.
+func newTraceID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("crzerolog: failed to generate trace ID: %v", err))
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// newSpanID returns a random non-zero span ID.
+func newSpanID() uint64 {
+	var b [8]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			panic(fmt.Sprintf("crzerolog: failed to generate span ID: %v", err))
+		}
+		if id := binary.BigEndian.Uint64(b[:]); id != 0 {
+			return id
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package crzerolog
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// reportedErrorEventType is the @type of log entries to be recognized by Error Reporting.
+// See: https://cloud.google.com/error-reporting/docs/formatting-error-messages
+const reportedErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// panicEvent returns a log event for the recovered panic p in Error Reporting format.
+func (l *Logger) panicEvent(logger *zerolog.Logger, p interface{}) *zerolog.Event {
+	// Error Reporting requires the stack trace in the same format as an unrecovered panic.
+	stack := fmt.Sprintf("panic: %v\n\n%s", p, debug.Stack())
+	event := logger.WithLevel(zerolog.PanicLevel).
+		Str("@type", reportedErrorEventType).
+		Str("stack_trace", stack)
+	// serviceContext is added by RootLogger if ResourceLabels is set.
+	if sc := serviceContext(); sc != nil && !l.config.ResourceLabels {
+		event = event.Dict("serviceContext", sc)
+	}
+	return event
+}
+
+// recoverHTTP recovers from panic in the HTTP handler, logs it and responds with 500 if possible.
+// It must be called by defer.
+func (l *Logger) recoverHTTP(logger *zerolog.Logger, w http.ResponseWriter, rw *responseWriter, r *http.Request) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	if p == http.ErrAbortHandler {
+		// ErrAbortHandler is used to abort the response intentionally.
+		panic(p)
+	}
+
+	statusCode := rw.status
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+	l.panicEvent(logger, p).
+		Dict("context", zerolog.Dict().Dict("httpRequest", zerolog.Dict().
+			Str("method", r.Method).
+			Str("url", requestURL(r)).
+			Str("userAgent", r.UserAgent()).
+			Str("referrer", r.Referer()).
+			Int("responseStatusCode", statusCode).
+			Str("remoteIp", remoteIP(r)))).
+		Msgf("panic: %v", p)
+
+	if rw.status == 0 {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// recoverRPC recovers from panic in the gRPC handler, logs it and sets codes.Internal error to err.
+// It must be called by defer.
+func (l *Logger) recoverRPC(logger *zerolog.Logger, method string, err *error) {
+	p := recover()
+	if p == nil {
+		return
+	}
+
+	l.panicEvent(logger, p).Str("method", method).Msgf("panic: %v", p)
+	*err = status.Error(codes.Internal, "internal error")
+}
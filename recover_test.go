@@ -0,0 +1,109 @@
+package crzerolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type errorStackEntry struct {
+	Function string `json:"function"`
+}
+
+type errorReportingEntry struct {
+	Severity       string              `json:"severity"`
+	Type           string              `json:"@type"`
+	StackTrace     string              `json:"stack_trace"`
+	Stack          errorStackEntry     `json:"stack"`
+	ServiceContext serviceContextEntry `json:"serviceContext"`
+	Trace          string              `json:"logging.googleapis.com/trace"`
+	Message        string              `json:"message"`
+}
+
+type serviceContextEntry struct {
+	Service string `json:"service"`
+	Version string `json:"version"`
+}
+
+func TestInjectLoggerRecoverPanic(t *testing.T) {
+	t.Setenv("K_SERVICE", "myservice")
+	t.Setenv("K_REVISION", "myservice-00001-abc")
+
+	logger := New(Config{ProjectID: "myproject", RecoverPanic: true})
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	resprec := httptest.NewRecorder()
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Add("X-Cloud-Trace-Context", "0123456789abcdef0123456789abcdef/123;o=1")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	logger.InjectLogger(&rootLogger)(handler).ServeHTTP(resprec, req)
+
+	if resprec.Code != http.StatusInternalServerError {
+		t.Errorf("Status code = %d, want = %d", resprec.Code, http.StatusInternalServerError)
+	}
+
+	var got errorReportingEntry
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(got.StackTrace, "panic: boom\n\ngoroutine ") {
+		t.Errorf("stack_trace is not in panic format: %q", got.StackTrace)
+	}
+
+	want := errorReportingEntry{
+		Severity:       "ALERT",
+		Type:           reportedErrorEventType,
+		StackTrace:     "ignore",
+		ServiceContext: serviceContextEntry{Service: "myservice", Version: "myservice-00001-abc"},
+		Trace:          "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+		Message:        "panic: boom",
+	}
+	opt := cmpopts.IgnoreFields(errorReportingEntry{}, "StackTrace")
+	if diff := cmp.Diff(want, got, opt); diff != "" {
+		t.Errorf("Log output diff: %s", diff)
+	}
+}
+
+func TestInjectLoggerInterceptorRecoverPanic(t *testing.T) {
+	logger := New(Config{ProjectID: "myproject", RecoverPanic: true})
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	unaryInfo := &grpc.UnaryServerInfo{
+		FullMethod: "TestService.TestMethod",
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.New(nil))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	}
+	_, err := logger.InjectLoggerInterceptor(&rootLogger)(ctx, nil, unaryInfo, handler)
+
+	if code := status.Code(err); code != codes.Internal {
+		t.Errorf("Status code = %v, want = %v", code, codes.Internal)
+	}
+
+	var got errorReportingEntry
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.Severity != "ALERT" || got.Type != reportedErrorEventType || !strings.HasPrefix(got.StackTrace, "panic: boom\n\n") {
+		t.Errorf("Log output is not in Error Reporting format: %+v", got)
+	}
+}
@@ -0,0 +1,44 @@
+package crzerolog
+
+import (
+	"context"
+	"unicode"
+)
+
+// RequestIDHeader is the default header to read the request ID from.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength is the maximum length of the request ID accepted from the client.
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key for the request ID.
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the request ID stored in ctx by the injectors with Config.RequestID.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// requestID returns the request ID from the header value, or a new random one
+// if the value is empty or invalid.
+func requestID(value string) string {
+	if isValidRequestID(value) {
+		return value
+	}
+	return newTraceID()
+}
+
+// isValidRequestID reports whether id is non-empty, not too long and has only printable ASCII characters,
+// so that a client can't break the log entries or the response header.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
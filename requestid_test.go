@@ -0,0 +1,91 @@
+package crzerolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestRequestID(t *testing.T) {
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+	tests := []struct {
+		desc   string
+		header http.Header
+		want   *regexp.Regexp
+	}{
+		{
+			desc:   "With X-Request-Id",
+			header: http.Header{"X-Request-Id": {"req-123"}},
+			want:   regexp.MustCompile(`^req-123$`),
+		},
+		{
+			desc:   "Without X-Request-Id",
+			header: http.Header{},
+			want:   generated,
+		},
+		{
+			desc:   "With invalid X-Request-Id",
+			header: http.Header{"X-Request-Id": {"req\n123"}},
+			want:   generated,
+		},
+	}
+
+	for _, tt := range tests {
+		logger := New(Config{ProjectID: "myproject", RequestID: true})
+		buf := &bytes.Buffer{}
+		rootLogger := logger.RootLogger(buf)
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+		var fromContext string
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fromContext, _ = RequestIDFromContext(r.Context())
+			log.Ctx(r.Context()).Info().Msg("hello")
+		})
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header = tt.header
+		resprec := httptest.NewRecorder()
+		logger.InjectLogger(&rootLogger)(handler).ServeHTTP(resprec, req)
+
+		if !tt.want.MatchString(fromContext) {
+			t.Errorf("%s: RequestIDFromContext = %q, want to match %s", tt.desc, fromContext, tt.want)
+		}
+		if got := resprec.Header().Get("X-Request-Id"); got != fromContext {
+			t.Errorf("%s: X-Request-Id = %q, want = %q", tt.desc, got, fromContext)
+		}
+		var got struct {
+			RequestID string `json:"requestId"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("%s: Unexpected error: %v", tt.desc, err)
+		}
+		if got.RequestID != fromContext {
+			t.Errorf("%s: requestId = %q, want = %q", tt.desc, got.RequestID, fromContext)
+		}
+	}
+}
+
+func TestRequestIDInterceptor(t *testing.T) {
+	logger := New(Config{ProjectID: "myproject", RequestID: true, RequestIDHeader: "X-Correlation-Id"})
+	rootLogger := logger.RootLogger(&bytes.Buffer{})
+
+	var got string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got, _ = RequestIDFromContext(ctx)
+		return nil, nil
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-correlation-id", "req-123"))
+	logger.InjectLoggerInterceptor(&rootLogger)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "TestService.TestMethod"}, handler)
+
+	if got != "req-123" {
+		t.Errorf("RequestIDFromContext = %q, want = %q", got, "req-123")
+	}
+}
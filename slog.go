@@ -0,0 +1,190 @@
+package crzerolog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/rs/zerolog"
+)
+
+// NewSlogHandler returns a slog.Handler which writes the records through rootLogger in Cloud Logging format.
+// It sets zerolog package-level variables for Cloud Logging on first call.
+func NewSlogHandler(rootLogger *zerolog.Logger) slog.Handler {
+	return std().NewSlogHandler(rootLogger)
+}
+
+// NewSlogHandler returns a slog.Handler which writes the records through rootLogger in Cloud Logging format.
+// If the context passed to the slog.Logger has the logger injected by InjectLogger or InjectLoggerInterceptor,
+// the record is written through it, so that it has the trace fields of the request.
+func (l *Logger) NewSlogHandler(rootLogger *zerolog.Logger) slog.Handler {
+	logger := l.callSiteHooks(rootLogger.Hook(l.timestampHook()))
+	return &slogHandler{logger: &logger, attrs: make([][]slog.Attr, 1)}
+}
+
+// slogHandler implements slog.Handler interface.
+type slogHandler struct {
+	logger *zerolog.Logger
+	// groups is the list of the groups opened by WithGroup.
+	groups []string
+	// attrs is the list of the attributes added by WithAttrs in each group.
+	// attrs[0] is the top-level attributes, and attrs[i] is in groups[i-1].
+	attrs [][]slog.Attr
+}
+
+// Enabled reports whether the handler handles records at the given level.
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	lvl := slogLevel(level)
+	return lvl >= h.contextLogger(ctx).GetLevel() && lvl >= zerolog.GlobalLevel()
+}
+
+// Handle writes the record through the logger in ctx if any, or the root logger otherwise.
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	e := h.contextLogger(ctx).WithLevel(slogLevel(r.Level))
+	if e == nil {
+		return nil
+	}
+	if r.PC != 0 {
+		// sourceLocation is derived from the PC of the record.
+		e = e.Ctx(context.WithValue(ctx, callerPCKey{}, r.PC))
+	} else {
+		e = e.Ctx(ctx)
+	}
+	h.addGroup(e, 0, r)
+	e.Msg(r.Message)
+	return nil
+}
+
+// WithAttrs returns a new handler with attrs added to the current group.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := h.clone()
+	last := len(h2.attrs) - 1
+	h2.attrs[last] = append(append([]slog.Attr{}, h2.attrs[last]...), attrs...)
+	return h2
+}
+
+// WithGroup returns a new handler with the group opened.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := h.clone()
+	h2.groups = append(h2.groups, name)
+	h2.attrs = append(h2.attrs, nil)
+	return h2
+}
+
+func (h *slogHandler) clone() *slogHandler {
+	return &slogHandler{
+		logger: h.logger,
+		groups: append([]string{}, h.groups...),
+		attrs:  append([][]slog.Attr{}, h.attrs...),
+	}
+}
+
+// contextLogger returns the logger in ctx if any, or the root logger otherwise.
+func (h *slogHandler) contextLogger(ctx context.Context) *zerolog.Logger {
+	if ctx != nil {
+		if logger := zerolog.Ctx(ctx); logger.GetLevel() != zerolog.Disabled {
+			return logger
+		}
+	}
+	return h.logger
+}
+
+// addGroup adds the attributes of the group at depth and the record attributes to e.
+func (h *slogHandler) addGroup(e *zerolog.Event, depth int, r slog.Record) {
+	for _, a := range h.attrs[depth] {
+		addSlogAttr(e, a)
+	}
+	if depth == len(h.groups) {
+		r.Attrs(func(a slog.Attr) bool {
+			addSlogAttr(e, a)
+			return true
+		})
+		return
+	}
+	if !h.hasAttrs(depth+1, r) {
+		// Empty groups are ignored.
+		return
+	}
+	dict := zerolog.Dict()
+	h.addGroup(dict, depth+1, r)
+	e.Dict(h.groups[depth], dict)
+}
+
+// hasAttrs reports whether the group at depth or its descendants have any attributes.
+func (h *slogHandler) hasAttrs(depth int, r slog.Record) bool {
+	for _, attrs := range h.attrs[depth:] {
+		if len(attrs) > 0 {
+			return true
+		}
+	}
+	return r.NumAttrs() > 0
+}
+
+// addSlogAttr adds the attribute to e.
+func addSlogAttr(e *zerolog.Event, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		e.Str(a.Key, a.Value.String())
+	case slog.KindInt64:
+		e.Int64(a.Key, a.Value.Int64())
+	case slog.KindUint64:
+		e.Uint64(a.Key, a.Value.Uint64())
+	case slog.KindFloat64:
+		e.Float64(a.Key, a.Value.Float64())
+	case slog.KindBool:
+		e.Bool(a.Key, a.Value.Bool())
+	case slog.KindDuration:
+		e.Dur(a.Key, a.Value.Duration())
+	case slog.KindTime:
+		e.Time(a.Key, a.Value.Time())
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return
+		}
+		if a.Key == "" {
+			// Groups with empty key are inlined.
+			for _, ga := range attrs {
+				addSlogAttr(e, ga)
+			}
+			return
+		}
+		dict := zerolog.Dict()
+		for _, ga := range attrs {
+			addSlogAttr(dict, ga)
+		}
+		e.Dict(a.Key, dict)
+	default:
+		if err, ok := a.Value.Any().(error); ok {
+			e.AnErr(a.Key, err)
+			return
+		}
+		e.Interface(a.Key, a.Value.Any())
+	}
+}
+
+// slogLevel converts slog.Level to zerolog.Level.
+func slogLevel(level slog.Level) zerolog.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return zerolog.TraceLevel
+	case level < slog.LevelInfo:
+		return zerolog.DebugLevel
+	case level < slog.LevelWarn:
+		return zerolog.InfoLevel
+	case level < slog.LevelError:
+		return zerolog.WarnLevel
+	default:
+		return zerolog.ErrorLevel
+	}
+}
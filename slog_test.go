@@ -0,0 +1,145 @@
+package crzerolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/rs/zerolog"
+)
+
+func TestSlogHandler(t *testing.T) {
+	tests := []struct {
+		desc    string
+		logFunc func(ctx context.Context, logger *slog.Logger)
+		want    map[string]interface{}
+	}{
+		{
+			desc: "Info",
+			logFunc: func(ctx context.Context, logger *slog.Logger) {
+				logger.InfoContext(ctx, "hello", "user", "alice", "count", 3)
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "hello",
+				"user":     "alice",
+				"count":    float64(3),
+			},
+		},
+		{
+			desc: "Warn with error",
+			logFunc: func(ctx context.Context, logger *slog.Logger) {
+				logger.WarnContext(ctx, "failed", "err", errors.New("boom"))
+			},
+			want: map[string]interface{}{
+				"severity": "WARNING",
+				"message":  "failed",
+				"err":      "boom",
+			},
+		},
+		{
+			desc: "Debug is ignored",
+			logFunc: func(ctx context.Context, logger *slog.Logger) {
+				logger.DebugContext(ctx, "hi")
+				logger.ErrorContext(ctx, "hello")
+			},
+			want: map[string]interface{}{
+				"severity": "ERROR",
+				"message":  "hello",
+			},
+		},
+		{
+			desc: "Groups and attributes",
+			logFunc: func(ctx context.Context, logger *slog.Logger) {
+				logger.With("app", "a").WithGroup("req").With("id", "1").WithGroup("empty").InfoContext(ctx, "hello")
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "hello",
+				"app":      "a",
+				"req": map[string]interface{}{
+					"id": "1",
+				},
+			},
+		},
+		{
+			desc: "Nested groups",
+			logFunc: func(ctx context.Context, logger *slog.Logger) {
+				logger.WithGroup("req").InfoContext(ctx, "hello", slog.Group("user", "name", "alice"), slog.Group("", "inline", true))
+			},
+			want: map[string]interface{}{
+				"severity": "INFO",
+				"message":  "hello",
+				"req": map[string]interface{}{
+					"user":   map[string]interface{}{"name": "alice"},
+					"inline": true,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		logger := New(Config{ProjectID: "myproject"})
+		buf := &bytes.Buffer{}
+		rootLogger := logger.RootLogger(buf)
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+		tt.logFunc(context.Background(), slog.New(logger.NewSlogHandler(&rootLogger)))
+
+		var got map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("%s: Unexpected error: %v", tt.desc, err)
+		}
+		loc, _ := got["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+		if loc["file"] != "slog_test.go" {
+			t.Errorf("%s: sourceLocation.file = %v, want = slog_test.go", tt.desc, loc["file"])
+		}
+		opt := cmpopts.IgnoreMapEntries(func(k string, v interface{}) bool {
+			return k == "time" || k == "logging.googleapis.com/sourceLocation"
+		})
+		if diff := cmp.Diff(tt.want, got, opt); diff != "" {
+			t.Errorf("%s: Log output diff: %s", tt.desc, diff)
+		}
+	}
+}
+
+func TestSlogHandlerWithInjectLogger(t *testing.T) {
+	logger := New(Config{ProjectID: "myproject"})
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	slogger := slog.New(logger.NewSlogHandler(&rootLogger))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slogger.InfoContext(r.Context(), "hello")
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Add("X-Cloud-Trace-Context", "0123456789abcdef0123456789abcdef/123;o=1")
+	logger.InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	var got logEntry
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := logEntry{
+		Severity: "INFO",
+		SourceLocation: sourceLocation{
+			File: "slog_test.go",
+		},
+		Trace:        "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+		SpanID:       "000000000000007b",
+		TraceSampled: true,
+		Message:      "hello",
+	}
+	opt := cmpopts.IgnoreFields(logEntry{}, "Time", "SourceLocation.Line", "SourceLocation.Function")
+	if diff := cmp.Diff(want, got, opt); diff != "" {
+		t.Errorf("Log output diff: %s", diff)
+	}
+}
@@ -0,0 +1,37 @@
+package crzerolog
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// spanLogger returns a child logger of logger which adds the trace fields
+// from the span in the event context at log time, if ctx has a span.
+func (l *Logger) spanLogger(ctx context.Context, logger zerolog.Logger) (zerolog.Logger, bool) {
+	if _, ok := l.config.TraceFromContext(ctx); !ok {
+		return logger, false
+	}
+	// The request context is used if the log event has no context.
+	return logger.With().Ctx(ctx).Logger().Hook(&spanContextHook{l}), true
+}
+
+// spanContextHook implements zerolog.Hook interface.
+type spanContextHook struct {
+	logger *Logger
+}
+
+// Run adds the trace fields from the span in the event context to zerolog.Event.
+func (h *spanContextHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level == zerolog.Disabled {
+		// Discarded by levelHook.
+		return
+	}
+	trace, ok := h.logger.config.TraceFromContext(e.GetCtx())
+	if !ok {
+		return
+	}
+	e.Str("logging.googleapis.com/trace", h.logger.traceName(trace.TraceID)).
+		Str("logging.googleapis.com/spanId", trace.SpanID).
+		Bool("logging.googleapis.com/trace_sampled", trace.Sampled)
+}
@@ -0,0 +1,128 @@
+package crzerolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// testSpanKey is the context key for the span of the tests.
+type testSpanKey struct{}
+
+func withTestSpan(ctx context.Context, spanID string) context.Context {
+	return context.WithValue(ctx, testSpanKey{}, TraceContext{
+		TraceID: "0123456789abcdef0123456789abcdef",
+		SpanID:  spanID,
+		Sampled: true,
+	})
+}
+
+func traceFromTestSpan(ctx context.Context) (TraceContext, bool) {
+	trace, ok := ctx.Value(testSpanKey{}).(TraceContext)
+	return trace, ok
+}
+
+func TestInjectLoggerWithSpan(t *testing.T) {
+
+	tests := []struct {
+		desc    string
+		ctx     context.Context
+		handler http.Handler
+		want    logEntry
+	}{
+		{
+			desc: "Request span",
+			ctx:  withTestSpan(context.Background(), "000000000000007b"),
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger := log.Ctx(r.Context())
+				logger.Info().Msg("hello")
+			}),
+			want: logEntry{
+				Time:     "ignore",
+				Severity: "INFO",
+				SourceLocation: sourceLocation{
+					File:     "span_test.go",
+					Line:     "ignore",
+					Function: "ignore",
+				},
+				Trace:        "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				SpanID:       "000000000000007b",
+				TraceSampled: true,
+				Message:      "hello",
+			},
+		},
+		{
+			desc: "Child span",
+			ctx:  withTestSpan(context.Background(), "000000000000007b"),
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctx := withTestSpan(r.Context(), "00000000000001c8")
+				logger := log.Ctx(ctx)
+				logger.Info().Ctx(ctx).Msg("hello")
+			}),
+			want: logEntry{
+				Time:     "ignore",
+				Severity: "INFO",
+				SourceLocation: sourceLocation{
+					File:     "span_test.go",
+					Line:     "ignore",
+					Function: "ignore",
+				},
+				Trace:        "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+				SpanID:       "00000000000001c8",
+				TraceSampled: true,
+				Message:      "hello",
+			},
+		},
+		{
+			desc: "Without span",
+			ctx:  context.Background(),
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger := log.Ctx(r.Context())
+				logger.Info().Msg("hello")
+			}),
+			want: logEntry{
+				Time:     "ignore",
+				Severity: "INFO",
+				SourceLocation: sourceLocation{
+					File:     "span_test.go",
+					Line:     "ignore",
+					Function: "ignore",
+				},
+				Trace:   "",
+				Message: "hello",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		logger := New(Config{ProjectID: "myproject", TraceFromContext: traceFromTestSpan})
+		buf := &bytes.Buffer{}
+		rootLogger := logger.RootLogger(buf)
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+		resprec := httptest.NewRecorder()
+
+		req, err := http.NewRequestWithContext(tt.ctx, "GET", "/", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		logger.InjectLogger(&rootLogger)(tt.handler).ServeHTTP(resprec, req)
+
+		var got logEntry
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		opt := cmpopts.IgnoreFields(logEntry{}, "Time", "SourceLocation.Line", "SourceLocation.Function")
+		if diff := cmp.Diff(tt.want, got, opt); diff != "" {
+			t.Errorf("%s: Log output diff: %s", tt.desc, diff)
+		}
+	}
+}
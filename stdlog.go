@@ -0,0 +1,81 @@
+package crzerolog
+
+import (
+	"context"
+	"io"
+	"log"
+	"runtime"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// NewStdLogger returns a *log.Logger of the standard library which writes the lines through rootLogger at level.
+// It sets zerolog package-level variables for Cloud Logging on first call.
+func NewStdLogger(rootLogger *zerolog.Logger, level zerolog.Level) *log.Logger {
+	return std().NewStdLogger(rootLogger, level)
+}
+
+// NewStdLogger returns a *log.Logger of the standard library which writes the lines through rootLogger at level.
+func (l *Logger) NewStdLogger(rootLogger *zerolog.Logger, level zerolog.Level) *log.Logger {
+	return log.New(l.NewStdWriter(rootLogger, level), "", 0)
+}
+
+// NewStdWriter returns an io.Writer which writes each line through rootLogger at level.
+// It is intended for the output of the standard log package, e.g. log.SetOutput.
+// It sets zerolog package-level variables for Cloud Logging on first call.
+func NewStdWriter(rootLogger *zerolog.Logger, level zerolog.Level) io.Writer {
+	return std().NewStdWriter(rootLogger, level)
+}
+
+// NewStdWriter returns an io.Writer which writes each line through rootLogger at level.
+// It is intended for the output of the standard log package, e.g. log.SetOutput.
+func (l *Logger) NewStdWriter(rootLogger *zerolog.Logger, level zerolog.Level) io.Writer {
+	logger := l.callSiteHooks(rootLogger.Hook(l.timestampHook()))
+	return &stdWriter{logger: logger, level: level}
+}
+
+// ServerErrorLog returns a *log.Logger for http.Server.ErrorLog which writes the errors through rootLogger at ErrorLevel.
+// It sets zerolog package-level variables for Cloud Logging on first call.
+func ServerErrorLog(rootLogger *zerolog.Logger) *log.Logger {
+	return std().ServerErrorLog(rootLogger)
+}
+
+// ServerErrorLog returns a *log.Logger for http.Server.ErrorLog which writes the errors through rootLogger at ErrorLevel.
+func (l *Logger) ServerErrorLog(rootLogger *zerolog.Logger) *log.Logger {
+	return l.NewStdLogger(rootLogger, zerolog.ErrorLevel)
+}
+
+// stdWriter implements io.Writer interface.
+type stdWriter struct {
+	logger zerolog.Logger
+	level  zerolog.Level
+}
+
+// Write writes p as the message of a log entry.
+func (w *stdWriter) Write(p []byte) (int, error) {
+	e := w.logger.WithLevel(w.level)
+	if e == nil {
+		return len(p), nil
+	}
+	if pc := stdLogCaller(); pc != 0 {
+		e = e.Ctx(context.WithValue(context.Background(), callerPCKey{}, pc))
+	}
+	e.Msg(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// stdLogCaller returns the program counter of the caller of the standard log package,
+// or the caller of stdWriter.Write if it's written directly.
+func stdLogCaller() uintptr {
+	pcs := make([]uintptr, 16)
+	// Skip runtime.Callers, stdLogCaller and stdWriter.Write.
+	n := runtime.Callers(3, pcs)
+	for _, pc := range pcs[:n] {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if !strings.HasPrefix(frame.Function, "log.") {
+			return pc
+		}
+	}
+	return 0
+}
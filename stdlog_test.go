@@ -0,0 +1,87 @@
+package crzerolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/rs/zerolog"
+)
+
+func TestStdLogger(t *testing.T) {
+	tests := []struct {
+		desc    string
+		level   zerolog.Level
+		logFunc func(logger *Logger, rootLogger *zerolog.Logger)
+		want    logEntry
+	}{
+		{
+			desc:  "Printf",
+			level: zerolog.WarnLevel,
+			logFunc: func(logger *Logger, rootLogger *zerolog.Logger) {
+				logger.NewStdLogger(rootLogger, zerolog.WarnLevel).Printf("hello %s", "world")
+			},
+			want: logEntry{
+				Severity:       "WARNING",
+				SourceLocation: sourceLocation{File: "stdlog_test.go"},
+				Message:        "hello world",
+			},
+		},
+		{
+			desc: "ServerErrorLog",
+			logFunc: func(logger *Logger, rootLogger *zerolog.Logger) {
+				logger.ServerErrorLog(rootLogger).Println("http: TLS handshake error")
+			},
+			want: logEntry{
+				Severity:       "ERROR",
+				SourceLocation: sourceLocation{File: "stdlog_test.go"},
+				Message:        "http: TLS handshake error",
+			},
+		},
+		{
+			desc: "Write directly",
+			logFunc: func(logger *Logger, rootLogger *zerolog.Logger) {
+				fmt.Fprintln(logger.NewStdWriter(rootLogger, zerolog.InfoLevel), "hello")
+			},
+			want: logEntry{
+				Severity: "INFO",
+				// fmt.Fprintln is the caller of the writer.
+				SourceLocation: sourceLocation{File: "print.go"},
+				Message:        "hello",
+			},
+		},
+		{
+			desc: "Below level",
+			logFunc: func(logger *Logger, rootLogger *zerolog.Logger) {
+				logger.NewStdLogger(rootLogger, zerolog.DebugLevel).Print("hi")
+				logger.NewStdLogger(rootLogger, zerolog.InfoLevel).Print("hello")
+			},
+			want: logEntry{
+				Severity:       "INFO",
+				SourceLocation: sourceLocation{File: "stdlog_test.go"},
+				Message:        "hello",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		logger := New(Config{ProjectID: "myproject"})
+		buf := &bytes.Buffer{}
+		rootLogger := logger.RootLogger(buf)
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+		tt.logFunc(logger, &rootLogger)
+
+		var got logEntry
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("%s: Unexpected error: %v", tt.desc, err)
+		}
+		opt := cmpopts.IgnoreFields(logEntry{}, "Time", "SourceLocation.Line", "SourceLocation.Function")
+		if diff := cmp.Diff(tt.want, got, opt); diff != "" {
+			t.Errorf("%s: Log output diff: %s", tt.desc, diff)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package crzerolog
+
+import "context"
+
+// traceKey is the context key for TraceContext.
+type traceKey struct{}
+
+// TraceContext is the trace of the request, which is stored in the request context
+// by InjectLogger, InjectLoggerInterceptor and InjectLoggerStreamInterceptor.
+type TraceContext struct {
+	// ProjectID is the project ID of the trace, or empty if it can't be resolved.
+	ProjectID string
+
+	// TraceID is the trace ID, e.g. "0123456789abcdef0123456789abcdef".
+	TraceID string
+
+	// SpanID is the span ID in 16-character hexadecimal number, or empty if not present.
+	SpanID string
+
+	// Sampled reports whether the trace is sampled.
+	Sampled bool
+
+	// Name is the resource name of the trace, e.g. "projects/myproject/traces/0123456789abcdef0123456789abcdef".
+	// It's the value of logging.googleapis.com/trace field.
+	Name string
+}
+
+// TraceFromContext returns the trace of the request stored in ctx.
+// It returns false if ctx has no trace, e.g. the request has no trace header.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	trace, ok := ctx.Value(traceKey{}).(TraceContext)
+	return trace, ok
+}
@@ -0,0 +1,154 @@
+package crzerolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTraceFromContext(t *testing.T) {
+	tests := []struct {
+		desc   string
+		header http.Header
+		want   TraceContext
+		wantOK bool
+	}{
+		{
+			desc:   "With X-Cloud-Trace-Context",
+			header: http.Header{"X-Cloud-Trace-Context": {"0123456789abcdef0123456789abcdef/123;o=1"}},
+			want: TraceContext{
+				ProjectID: "myproject",
+				TraceID:   "0123456789abcdef0123456789abcdef",
+				SpanID:    "000000000000007b",
+				Sampled:   true,
+				Name:      "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+			},
+			wantOK: true,
+		},
+		{
+			desc:   "With traceparent",
+			header: http.Header{"Traceparent": {"00-0123456789abcdef0123456789abcdef-000000000000007b-00"}},
+			want: TraceContext{
+				ProjectID: "myproject",
+				TraceID:   "0123456789abcdef0123456789abcdef",
+				SpanID:    "000000000000007b",
+				Sampled:   false,
+				Name:      "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+			},
+			wantOK: true,
+		},
+		{
+			desc:   "Without trace header",
+			header: http.Header{},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		logger := New(Config{ProjectID: "myproject"})
+		rootLogger := logger.RootLogger(&bytes.Buffer{})
+
+		var got TraceContext
+		var gotOK bool
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, gotOK = TraceFromContext(r.Context())
+		})
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header = tt.header
+		logger.InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		if gotOK != tt.wantOK {
+			t.Errorf("%s: ok = %v, want = %v", tt.desc, gotOK, tt.wantOK)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: TraceContext diff: %s", tt.desc, diff)
+		}
+
+		// The gRPC interceptor stores the same trace.
+		md := metadata.MD{}
+		for k, v := range tt.header {
+			md.Set(k, v...)
+		}
+		got, gotOK = TraceContext{}, false
+		unaryHandler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			got, gotOK = TraceFromContext(ctx)
+			return nil, nil
+		}
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		logger.InjectLoggerInterceptor(&rootLogger)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "TestService.TestMethod"}, unaryHandler)
+
+		if gotOK != tt.wantOK {
+			t.Errorf("%s: gRPC: ok = %v, want = %v", tt.desc, gotOK, tt.wantOK)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: gRPC: TraceContext diff: %s", tt.desc, diff)
+		}
+	}
+}
+
+func TestGenerateTrace(t *testing.T) {
+	tests := []struct {
+		desc          string
+		header        http.Header
+		wantGenerated bool
+	}{
+		{
+			desc:          "Without trace header",
+			header:        http.Header{},
+			wantGenerated: true,
+		},
+		{
+			desc:          "With X-Cloud-Trace-Context",
+			header:        http.Header{"X-Cloud-Trace-Context": {"0123456789abcdef0123456789abcdef/123;o=1"}},
+			wantGenerated: false,
+		},
+	}
+
+	for _, tt := range tests {
+		logger := New(Config{ProjectID: "myproject", GenerateTrace: true, TraceResponseHeader: "X-Trace-Id"})
+		buf := &bytes.Buffer{}
+		rootLogger := logger.RootLogger(buf)
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+		var trace TraceContext
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			trace, _ = TraceFromContext(r.Context())
+			log.Ctx(r.Context()).Info().Msg("hello")
+		})
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header = tt.header
+		resprec := httptest.NewRecorder()
+		logger.InjectLogger(&rootLogger)(handler).ServeHTTP(resprec, req)
+
+		if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(trace.TraceID) {
+			t.Errorf("%s: TraceID = %q, want 32-character hexadecimal", tt.desc, trace.TraceID)
+		}
+		if !regexp.MustCompile(`^[0-9a-f]{16}$`).MatchString(trace.SpanID) {
+			t.Errorf("%s: SpanID = %q, want 16-character hexadecimal", tt.desc, trace.SpanID)
+		}
+		if generated := trace.TraceID != "0123456789abcdef0123456789abcdef"; generated != tt.wantGenerated {
+			t.Errorf("%s: generated = %v, want = %v", tt.desc, generated, tt.wantGenerated)
+		}
+		if got := resprec.Header().Get("X-Trace-Id"); got != trace.TraceID {
+			t.Errorf("%s: X-Trace-Id = %q, want = %q", tt.desc, got, trace.TraceID)
+		}
+
+		var got logEntry
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("%s: Unexpected error: %v", tt.desc, err)
+		}
+		if want := "projects/myproject/traces/" + trace.TraceID; got.Trace != want {
+			t.Errorf("%s: trace = %q, want = %q", tt.desc, got.Trace, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package crzerolog
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Transport returns an http.RoundTripper which propagates the trace in the request context
+// injected by InjectLogger or InjectLoggerInterceptor to the outgoing request.
+// If base is nil, http.DefaultTransport is used.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return stdClient().Transport(base)
+}
+
+// Transport returns an http.RoundTripper which propagates the trace in the request context
+// injected by InjectLogger or InjectLoggerInterceptor to the outgoing request.
+// It sets X-Cloud-Trace-Context and traceparent headers with a new span ID, unless they are already set.
+// If base is nil, http.DefaultTransport is used.
+func (l *Logger) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{logger: l, base: base}
+}
+
+// transport implements http.RoundTripper interface.
+type transport struct {
+	logger *Logger
+	base   http.RoundTripper
+}
+
+// RoundTrip sets the trace headers to the request and calls the underlying RoundTrip.
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if cloudTraceContext, traceparent, ok := outgoingTraceHeaders(r.Context()); ok {
+		// RoundTripper must not modify the given request.
+		r = r.Clone(r.Context())
+		if r.Header.Get(CloudTraceContextHeader) == "" {
+			r.Header.Set(CloudTraceContextHeader, cloudTraceContext)
+		}
+		if r.Header.Get(TraceparentHeader) == "" && traceparent != "" {
+			r.Header.Set(TraceparentHeader, traceparent)
+		}
+	}
+
+	if !t.logger.config.ClientLog {
+		return t.base.RoundTrip(r)
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(r)
+	writeClientRequestLog(log.Ctx(r.Context()), r, resp, err, time.Since(start))
+	return resp, err
+}
+
+// writeClientRequestLog writes a log entry with the httpRequest field for the outgoing request.
+func writeClientRequestLog(logger *zerolog.Logger, r *http.Request, resp *http.Response, err error, latency time.Duration) {
+	var event *zerolog.Event
+	switch {
+	case err != nil || resp.StatusCode >= 500:
+		event = logger.Error()
+	case resp.StatusCode >= 400:
+		event = logger.Warn()
+	default:
+		event = logger.Info()
+	}
+
+	req := zerolog.Dict().
+		Str("requestMethod", r.Method).
+		Str("requestUrl", r.URL.String()).
+		Str("latency", formatDuration(latency)).
+		Str("userAgent", r.UserAgent())
+	if r.ContentLength > 0 {
+		req = req.Str("requestSize", strconv.FormatInt(r.ContentLength, 10))
+	}
+	if resp != nil {
+		req = req.Int("status", resp.StatusCode).Str("protocol", resp.Proto)
+		if resp.ContentLength >= 0 {
+			req = req.Str("responseSize", strconv.FormatInt(resp.ContentLength, 10))
+		}
+	}
+	if err != nil {
+		event = event.Err(err)
+	}
+	event.Dict("httpRequest", req).Send()
+}
@@ -0,0 +1,152 @@
+package crzerolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rs/zerolog"
+)
+
+func TestTransport(t *testing.T) {
+	tests := []struct {
+		desc              string
+		header            http.Header
+		wantCloudTrace    *regexp.Regexp
+		wantTraceparent   *regexp.Regexp
+		wantNoTraceHeader bool
+	}{
+		{
+			desc:            "With X-Cloud-Trace-Context",
+			header:          http.Header{"X-Cloud-Trace-Context": {"0123456789abcdef0123456789abcdef/123;o=1"}},
+			wantCloudTrace:  regexp.MustCompile(`^0123456789abcdef0123456789abcdef/\d+;o=1$`),
+			wantTraceparent: regexp.MustCompile(`^00-0123456789abcdef0123456789abcdef-[0-9a-f]{16}-01$`),
+		},
+		{
+			desc:            "With traceparent not sampled",
+			header:          http.Header{"Traceparent": {"00-0123456789abcdef0123456789abcdef-000000000000007b-00"}},
+			wantCloudTrace:  regexp.MustCompile(`^0123456789abcdef0123456789abcdef/\d+;o=0$`),
+			wantTraceparent: regexp.MustCompile(`^00-0123456789abcdef0123456789abcdef-[0-9a-f]{16}-00$`),
+		},
+		{
+			desc:              "Without trace header",
+			header:            http.Header{},
+			wantNoTraceHeader: true,
+		},
+	}
+
+	for _, tt := range tests {
+		var got http.Header
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header
+		}))
+		defer backend.Close()
+
+		logger := New(Config{ProjectID: "myproject"})
+		rootLogger := logger.RootLogger(&bytes.Buffer{})
+		client := &http.Client{Transport: logger.Transport(nil)}
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req, err := http.NewRequestWithContext(r.Context(), "GET", backend.URL, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			resp.Body.Close()
+			if req.Header.Get(CloudTraceContextHeader) != "" {
+				t.Errorf("%s: the original request is modified", tt.desc)
+			}
+		})
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header = tt.header
+		logger.InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+		if tt.wantNoTraceHeader {
+			if got.Get(CloudTraceContextHeader) != "" || got.Get(TraceparentHeader) != "" {
+				t.Errorf("%s: unexpected trace headers: %v", tt.desc, got)
+			}
+			continue
+		}
+		if v := got.Get(CloudTraceContextHeader); !tt.wantCloudTrace.MatchString(v) {
+			t.Errorf("%s: X-Cloud-Trace-Context = %q, want to match %s", tt.desc, v, tt.wantCloudTrace)
+		}
+		v := got.Get(TraceparentHeader)
+		if !tt.wantTraceparent.MatchString(v) {
+			t.Errorf("%s: traceparent = %q, want to match %s", tt.desc, v, tt.wantTraceparent)
+		}
+		if strings.Contains(v, "000000000000007b") {
+			t.Errorf("%s: traceparent = %q, want a new span ID", tt.desc, v)
+		}
+	}
+}
+
+func TestTransportClientLog(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer backend.Close()
+
+	logger := New(Config{ProjectID: "myproject", ClientLog: true})
+	buf := &bytes.Buffer{}
+	rootLogger := logger.RootLogger(buf)
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	client := &http.Client{Transport: logger.Transport(nil)}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(r.Context(), "GET", backend.URL+"/foo", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Add("X-Cloud-Trace-Context", "0123456789abcdef0123456789abcdef/123;o=1")
+	logger.InjectLogger(&rootLogger)(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+	var got requestLogEntry
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := requestLogEntry{
+		Severity: "WARNING",
+		Trace:    "projects/myproject/traces/0123456789abcdef0123456789abcdef",
+		HTTPRequest: httpRequest{
+			RequestMethod: "GET",
+			RequestURL:    backend.URL + "/foo",
+			Status:        http.StatusNotFound,
+			ResponseSize:  "0",
+			UserAgent:     "",
+			Protocol:      "HTTP/1.1",
+		},
+	}
+	if got.HTTPRequest.Latency == "" {
+		t.Errorf("latency is empty")
+	}
+	got.HTTPRequest.Latency = ""
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Log output diff: %s", diff)
+	}
+}
+
+func TestTransportDoesNotModifyGlobals(t *testing.T) {
+	useDefaultLogger(t, "myproject")
+	levelFieldName, timeFieldFormat := zerolog.LevelFieldName, zerolog.TimeFieldFormat
+	Transport(nil)
+	TraceClientInterceptor()
+	TraceClientStreamInterceptor()
+
+	if zerolog.LevelFieldName != levelFieldName || zerolog.TimeFieldFormat != timeFieldFormat {
+		t.Errorf("Transport modified zerolog package-level variables")
+	}
+}